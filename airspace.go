@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AirspaceFeature is a no-fly zone returned by the airspace queries: the
+// original GeoJSON properties and geometry, with how the planner treats the
+// zone as a foreign member
+type AirspaceFeature struct {
+	GeoJSONFeature
+	Planner AirspaceZoneInfo `json:"planner"`
+}
+
+// AirspaceZoneInfo describes how the planner treats a zone
+type AirspaceZoneInfo struct {
+	Category   ZoneCategory `json:"category"`
+	Buffer     float64      `json:"buffer,omitempty"`     // Safety clearance in meters
+	Floor      float64      `json:"floor,omitempty"`      // Lower vertical limit in meters above ground level
+	Ceiling    float64      `json:"ceiling,omitempty"`    // Upper vertical limit in meters above ground level, 0 for unlimited
+	Temporary  bool         `json:"temporary,omitempty"`  // Added as a temporary zone
+	ValidFrom  *time.Time   `json:"validFrom,omitempty"`  // Start of a temporary zone's validity
+	ValidTo    *time.Time   `json:"validTo,omitempty"`    // End of a temporary zone's validity
+	BufferOnly bool         `json:"bufferOnly,omitempty"` // The point lies in the safety buffer, outside the zone
+}
+
+// AirspaceResponse is a GeoJSON FeatureCollection of the zones found by a query
+type AirspaceResponse struct {
+	Type     string            `json:"type"`
+	Features []AirspaceFeature `json:"features"`
+}
+
+// airspaceFeature converts a zone to a GeoJSON Polygon feature carrying its
+// original properties; parts of MultiPolygon zones are returned separately
+func airspaceFeature(zone Polygon, temporary bool) AirspaceFeature {
+	rings := make([][][]float64, 0, len(zone.Holes)+1)
+	for _, ring := range zone.rings() {
+		coords := make([][]float64, len(ring))
+		for i, p := range ring {
+			coords[i] = []float64{p.X, p.Y}
+		}
+		rings = append(rings, coords)
+	}
+	data, _ := json.Marshal(rings)
+
+	properties := zone.Properties
+	if properties == nil {
+		properties = map[string]interface{}{}
+	}
+	return AirspaceFeature{
+		GeoJSONFeature: GeoJSONFeature{
+			Type:       "Feature",
+			Geometry:   GeoJSONGeometry{Type: "Polygon", Coordinates: data},
+			Properties: properties,
+		},
+		Planner: AirspaceZoneInfo{
+			Category:  zone.Category,
+			Buffer:    zone.Buffer,
+			Floor:     zone.Floor,
+			Ceiling:   zone.Ceiling,
+			Temporary: temporary,
+			ValidFrom: zone.ValidFrom,
+			ValidTo:   zone.ValidTo,
+		},
+	}
+}
+
+// airspaceIndexes returns the loaded no-fly zones and the temporary zones
+// active at a moment, the latter flagged as temporary
+func airspaceIndexes(at time.Time) ([]*ZoneIndex, []bool) {
+	_, zones := currentPlanningState()
+	indexes := []*ZoneIndex{zones}
+	temporary := []bool{false}
+	if temporaryZones := globalTemporaryZones.Index(); len(temporaryZones.Zones) > 0 {
+		indexes = append(indexes, temporaryZones.WithFilter(func(zone *Polygon) bool {
+			return zone.IsActiveDuring(at, at)
+		}))
+		temporary = append(temporary, true)
+	}
+	return indexes, temporary
+}
+
+// ZonesAtPoint returns the zones whose area or safety buffer contains a point,
+// at the given altitude or at any altitude when alt is NaN
+func ZonesAtPoint(p Point, alt float64, at time.Time) []AirspaceFeature {
+	low, high := math.Inf(-1), math.Inf(1)
+	if !math.IsNaN(alt) {
+		low, high = alt, alt
+	}
+
+	var features []AirspaceFeature
+	indexes, temporary := airspaceIndexes(at)
+	for i, zones := range indexes {
+		for _, zone := range zones.ZonesContainingPointBetween(p, low, high) {
+			feature := airspaceFeature(zones.Zones[zone], temporary[i])
+			feature.Planner.BufferOnly = !IsPointInPolygon(p, zones.Zones[zone])
+			features = append(features, feature)
+		}
+	}
+	return features
+}
+
+// ZonesInBoundingBox returns the zones whose area overlaps a bounding box
+func ZonesInBoundingBox(box BoundingBox, at time.Time) []AirspaceFeature {
+	var features []AirspaceFeature
+	indexes, temporary := airspaceIndexes(at)
+	for i, zones := range indexes {
+		zones.searchEnvelopes(box, math.Inf(-1), math.Inf(1), func(zone int) bool {
+			if polygonIntersectsBox(zones.Zones[zone], box) {
+				features = append(features, airspaceFeature(zones.Zones[zone], temporary[i]))
+			}
+			return true
+		})
+	}
+	return features
+}
+
+// polygonIntersectsBox reports whether a polygon and a bounding box overlap:
+// a vertex of the polygon lies in the box, a corner of the box lies in the
+// polygon, or their edges cross
+func polygonIntersectsBox(polygon Polygon, box BoundingBox) bool {
+	for _, v := range polygon.Vertices {
+		if box.Contains(v) {
+			return true
+		}
+	}
+
+	corners := []Point{{X: box.MinX, Y: box.MinY}, {X: box.MaxX, Y: box.MinY}, {X: box.MaxX, Y: box.MaxY}, {X: box.MinX, Y: box.MaxY}}
+	for i, corner := range corners {
+		if IsPointInPolygon(corner, polygon) {
+			return true
+		}
+		if DoesSegmentIntersectPolygon(LineSegment{P1: corner, P2: corners[(i+1)%4]}, polygon) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseQueryFloats reads a comma-separated list of count numbers from a query parameter
+func parseQueryFloats(value string, count int) ([]float64, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != count {
+		return nil, fmt.Errorf("expected %d comma-separated numbers", count)
+	}
+	numbers := make([]float64, count)
+	for i, part := range parts {
+		number, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, err
+		}
+		numbers[i] = number
+	}
+	return numbers, nil
+}
+
+// parseQueryTime reads the optional time query parameter, defaulting to now
+func parseQueryTime(r *http.Request) (time.Time, error) {
+	value := r.URL.Query().Get("time")
+	if value == "" {
+		return time.Now(), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// writeAirspaceResponse writes the zones found by a query as a FeatureCollection
+func writeAirspaceResponse(w http.ResponseWriter, features []AirspaceFeature) {
+	if features == nil {
+		features = []AirspaceFeature{}
+	}
+	log.Printf("✅ Found %d zones\n", len(features))
+	log.Println("========================================")
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	json.NewEncoder(w).Encode(AirspaceResponse{Type: "FeatureCollection", Features: features})
+}
+
+// GET /airspace/point?lon=<lon>&lat=<lat>[&alt=<m>][&time=<RFC 3339>] - List the zones restricting a location
+func airspacePointHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("========================================")
+	log.Println("🔍 Airspace point query received")
+
+	if r.Method != http.MethodGet {
+		log.Printf("❌ Method not allowed: %s\n", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	lon, lonErr := strconv.ParseFloat(query.Get("lon"), 64)
+	lat, latErr := strconv.ParseFloat(query.Get("lat"), 64)
+	if lonErr != nil || latErr != nil {
+		log.Println("❌ Invalid lon or lat")
+		http.Error(w, "Query parameters lon and lat are required", http.StatusBadRequest)
+		return
+	}
+	alt := math.NaN()
+	if value := query.Get("alt"); value != "" {
+		var err error
+		if alt, err = strconv.ParseFloat(value, 64); err != nil {
+			log.Printf("❌ Invalid alt: %v\n", err)
+			http.Error(w, "Query parameter alt must be a number of meters", http.StatusBadRequest)
+			return
+		}
+	}
+	at, err := parseQueryTime(r)
+	if err != nil {
+		log.Printf("❌ Invalid time: %v\n", err)
+		http.Error(w, "Query parameter time must be an RFC 3339 timestamp", http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("   Point: (%.6f, %.6f)\n", lon, lat)
+	writeAirspaceResponse(w, ZonesAtPoint(Point{X: lon, Y: lat}, alt, at))
+}
+
+// GET /airspace/bbox?bbox=<minLon>,<minLat>,<maxLon>,<maxLat>[&time=<RFC 3339>] - List the zones in an area
+func airspaceBoxHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("========================================")
+	log.Println("🔍 Airspace bounding box query received")
+
+	if r.Method != http.MethodGet {
+		log.Printf("❌ Method not allowed: %s\n", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	bbox, err := parseQueryFloats(r.URL.Query().Get("bbox"), 4)
+	if err != nil || bbox[0] > bbox[2] || bbox[1] > bbox[3] {
+		log.Printf("❌ Invalid bbox: %q\n", r.URL.Query().Get("bbox"))
+		http.Error(w, "Query parameter bbox must be minLon,minLat,maxLon,maxLat", http.StatusBadRequest)
+		return
+	}
+	at, err := parseQueryTime(r)
+	if err != nil {
+		log.Printf("❌ Invalid time: %v\n", err)
+		http.Error(w, "Query parameter time must be an RFC 3339 timestamp", http.StatusBadRequest)
+		return
+	}
+
+	box := BoundingBox{MinX: bbox[0], MinY: bbox[1], MaxX: bbox[2], MaxY: bbox[3]}
+	log.Printf("   Bounding box: (%.4f, %.4f) to (%.4f, %.4f)\n", box.MinX, box.MinY, box.MaxX, box.MaxY)
+	writeAirspaceResponse(w, ZonesInBoundingBox(box, at))
+}
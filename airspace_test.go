@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// useAirspace replaces the loaded and temporary zones for the duration of a
+// test, giving every zone its GID as a GeoJSON property
+func useAirspace(t *testing.T, zones, temporary []Polygon) {
+	for _, list := range [][]Polygon{zones, temporary} {
+		for i := range list {
+			list[i].Properties = map[string]interface{}{"gid": float64(list[i].GID)}
+		}
+	}
+
+	prmMutex.Lock()
+	oldZones, oldTemporary := globalZoneIndex, globalTemporaryZones
+	globalZoneIndex, globalTemporaryZones = NewZoneIndex(zones), NewTemporaryZoneStore(temporary)
+	prmMutex.Unlock()
+	t.Cleanup(func() {
+		prmMutex.Lock()
+		globalZoneIndex, globalTemporaryZones = oldZones, oldTemporary
+		prmMutex.Unlock()
+	})
+}
+
+func TestAirspacePointHandler(t *testing.T) {
+	banded := squareZone(5.0, 52.0, 0.1)
+	banded.GID, banded.Floor, banded.Ceiling, banded.Buffer = 1, 60, 120, 100
+	validFrom := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	validTo := validFrom.Add(time.Hour)
+	temporary := squareZone(5.0, 52.0, 0.05)
+	temporary.GID, temporary.ValidFrom, temporary.ValidTo = 2, &validFrom, &validTo
+	useAirspace(t, []Polygon{banded}, []Polygon{temporary})
+
+	tests := []struct {
+		name       string
+		query      string
+		wantGIDs   []int
+		bufferOnly bool
+	}{
+		{"any altitude", "lon=5.01&lat=52.01&time=2026-06-01T12:30:00Z", []int{1, 2}, false},
+		{"under the floor", "lon=5.01&lat=52.01&alt=30&time=2026-06-01T12:30:00Z", []int{2}, false},
+		{"temporary zone inactive", "lon=5.01&lat=52.01&alt=80&time=2026-06-01T14:00:00Z", []int{1}, false},
+		{"in the buffer", "lon=5.1005&lat=52.05&time=2026-06-01T12:30:00Z", []int{1}, true},
+		{"outside", "lon=5.2&lat=52.05", nil, false},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		airspacePointHandler(rec, httptest.NewRequest(http.MethodGet, "/airspace/point?"+tt.query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d", tt.name, rec.Code)
+		}
+		var response AirspaceResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		var gids []int
+		for _, feature := range response.Features {
+			gids = append(gids, int(feature.Properties["gid"].(float64)))
+			if feature.Planner.BufferOnly != tt.bufferOnly {
+				t.Errorf("%s: zone %v bufferOnly %v, want %v", tt.name, feature.Properties["gid"], feature.Planner.BufferOnly, tt.bufferOnly)
+			}
+		}
+		if !equalInts(sortedInts(gids), tt.wantGIDs) {
+			t.Errorf("%s: zones %v, want %v", tt.name, gids, tt.wantGIDs)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	airspacePointHandler(rec, httptest.NewRequest(http.MethodGet, "/airspace/point?lon=5", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("missing lat: status %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestZonesInBoundingBox(t *testing.T) {
+	// A box inside a zone contains none of its vertices, and a box around a
+	// zone has none of its corners in it
+	large := squareZone(5.0, 52.0, 1.0)
+	large.GID = 1
+	small := squareZone(6.5, 52.5, 0.01)
+	small.GID = 2
+	useAirspace(t, []Polygon{large, small}, nil)
+
+	tests := []struct {
+		name string
+		box  BoundingBox
+		want []int
+	}{
+		{"inside the large zone", BoundingBox{MinX: 5.4, MinY: 52.4, MaxX: 5.5, MaxY: 52.5}, []int{1}},
+		{"around the small zone", BoundingBox{MinX: 6.4, MinY: 52.4, MaxX: 6.6, MaxY: 52.6}, []int{2}},
+		{"across the large zone edge", BoundingBox{MinX: 5.9, MinY: 51.9, MaxX: 6.1, MaxY: 52.1}, []int{1}},
+		{"between the zones", BoundingBox{MinX: 6.1, MinY: 52.1, MaxX: 6.4, MaxY: 52.4}, nil},
+	}
+	for _, tt := range tests {
+		var gids []int
+		for _, feature := range ZonesInBoundingBox(tt.box, time.Now()) {
+			gids = append(gids, int(feature.Properties["gid"].(float64)))
+		}
+		if !equalInts(sortedInts(gids), tt.want) {
+			t.Errorf("%s: zones %v, want %v", tt.name, gids, tt.want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strings"
+)
+
+// maxAlternatives limits the alternative routes per request
+const maxAlternatives = 5
+
+// alternativeCorridorMeters is the distance from a route within which another
+// route is considered to fly the same corridor
+const alternativeCorridorMeters = 1500.0
+
+// alternativePenalty is the extra cost per meter an alternative flies inside
+// the corridor of earlier routes
+const alternativePenalty = 1.0
+
+// maxAlternativeOverlap is the largest share of an alternative that may lie
+// inside the corridor of any earlier route
+const maxAlternativeOverlap = 0.6
+
+// Corridor is the area within a distance of a set of paths sharing their start
+// and end, leaving out the area around the start and end that every route
+// between them has to cross
+type Corridor struct {
+	Paths  [][]Point
+	Meters float64
+}
+
+// Contains reports whether a point lies inside the corridor
+func (c *Corridor) Contains(p Point) bool {
+	if len(c.Paths) == 0 {
+		return false
+	}
+	first := c.Paths[0]
+	if p.HorizontalDistanceMeters(first[0]) <= c.Meters || p.HorizontalDistanceMeters(first[len(first)-1]) <= c.Meters {
+		return false
+	}
+	for _, path := range c.Paths {
+		for i := 0; i < len(path)-1; i++ {
+			if pointSegmentDistanceMeters(p, LineSegment{P1: path[i], P2: path[i+1]}) <= c.Meters {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// LengthInside returns the length of a segment inside the corridor, sampled
+// every half corridor width
+func (c *Corridor) LengthInside(from, to Point) float64 {
+	if c == nil {
+		return 0
+	}
+	length := from.HorizontalDistanceMeters(to)
+	steps := int(math.Ceil(length/(c.Meters/2))) + 1
+	inside := 0
+	for i := 0; i < steps; i++ {
+		s := (float64(i) + 0.5) / float64(steps)
+		if c.Contains(Point{X: from.X + s*(to.X-from.X), Y: from.Y + s*(to.Y-from.Y)}) {
+			inside++
+		}
+	}
+	return length * float64(inside) / float64(steps)
+}
+
+// Overlap returns the share of a path's length inside the corridor
+func (c *Corridor) Overlap(path []Point) float64 {
+	total, inside := 0.0, 0.0
+	for i := 0; i < len(path)-1; i++ {
+		total += path[i].HorizontalDistanceMeters(path[i+1])
+		inside += c.LengthInside(path[i], path[i+1])
+	}
+	if total == 0 {
+		return 1
+	}
+	return inside / total
+}
+
+// Alternative is a route that differs materially from the best route and the
+// alternatives before it
+type Alternative struct {
+	RoutePlan
+	Overlap float64 // Largest share of the route inside the corridor of an earlier route
+	Summary string  // How the route compares to the best route
+}
+
+// PlanAlternatives finds up to count routes through the stops that differ
+// materially from the best route and from each other
+// Every new route is planned with a cost penalty for flying inside the
+// corridor of all routes found so far, and is kept only when no more than
+// maxAlternativeOverlap of it lies inside the corridor of an earlier route
+func PlanAlternatives(graph *PRMGraph, stops []Point, best RoutePlan, count int, opts PlanOptions) []Alternative {
+	accepted := []RoutePlan{best}
+	avoid := &Corridor{Paths: [][]Point{best.Path}, Meters: alternativeCorridorMeters}
+	var alternatives []Alternative
+
+	for attempt := 1; len(alternatives) < count && attempt <= 2*count; attempt++ {
+		log.Printf("🔀 Planning alternative route (attempt %d, avoiding %d routes)...\n", attempt, len(avoid.Paths))
+		opts.Avoid = avoid
+		plan := PlanRoute(graph, stops, opts)
+		if !plan.Success {
+			log.Printf("   ⚠️  No further alternative: %s\n", plan.Message)
+			break
+		}
+		avoid = &Corridor{Paths: append(avoid.Paths[:len(avoid.Paths):len(avoid.Paths)], plan.Path), Meters: avoid.Meters}
+
+		overlap := 0.0
+		for _, route := range accepted {
+			corridor := &Corridor{Paths: [][]Point{route.Path}, Meters: alternativeCorridorMeters}
+			overlap = math.Max(overlap, corridor.Overlap(plan.Path))
+		}
+		if overlap > maxAlternativeOverlap {
+			log.Printf("   ⚠️  Rejected: %.0f%% inside the corridor of an earlier route\n", overlap*100)
+			continue
+		}
+
+		accepted = append(accepted, plan)
+		alternative := Alternative{RoutePlan: plan, Overlap: overlap, Summary: alternativeSummary(best, plan, overlap)}
+		alternatives = append(alternatives, alternative)
+		log.Printf("   ✅ Alternative %d: %s\n", len(alternatives), alternative.Summary)
+	}
+	return alternatives
+}
+
+// alternativeSummary describes how an alternative compares to the best route
+func alternativeSummary(best, plan RoutePlan, overlap float64) string {
+	delta := plan.DistanceMeters - best.DistanceMeters
+	longer := "longer"
+	if delta < 0 {
+		longer = "shorter"
+	}
+	// A best route of zero length, from a stop back to itself, has no relative change
+	change := 0.0
+	if best.DistanceMeters > 0 {
+		change = delta / best.DistanceMeters
+	}
+	parts := []string{fmt.Sprintf("%.1f km %s than the best route (%+.0f%%)",
+		math.Abs(delta)/1000, longer, change*100)}
+
+	if restricted := plan.RestrictedDistanceMeters - best.RestrictedDistanceMeters; math.Abs(restricted) >= 100 {
+		more := "more"
+		if restricted < 0 {
+			more = "less"
+		}
+		parts = append(parts, fmt.Sprintf("%.1f km %s inside restricted zones", math.Abs(restricted)/1000, more))
+	}
+	if plan.Timing != nil && best.Timing != nil {
+		parts = append(parts, fmt.Sprintf("%+.0f min flight time", (plan.Timing.TotalSeconds-best.Timing.TotalSeconds)/60))
+	}
+	if stops := len(plan.ChargingStops) - len(best.ChargingStops); stops != 0 {
+		parts = append(parts, fmt.Sprintf("%+d charging stops", stops))
+	}
+	parts = append(parts, fmt.Sprintf("%.0f%% shared with earlier routes", overlap*100))
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlanAlternativesDifferFromBestRoute(t *testing.T) {
+	zone := squareZone(5.12, 52.06, 0.06)
+	opts := testPlanOptions([]Polygon{zone}, nil)
+	graph := gridGraph(opts.Zones.WithFilter(isHardZone))
+
+	stops := []Point{{X: 5.02, Y: 52.09}, {X: 5.28, Y: 52.09}}
+	best := PlanRoute(graph, stops, opts)
+	if !best.Success {
+		t.Fatalf("no best route: %s", best.Message)
+	}
+	alternatives := PlanAlternatives(graph, stops, best, 2, opts)
+	if len(alternatives) == 0 {
+		t.Fatal("no alternative around the other side of the zone")
+	}
+
+	corridor := &Corridor{Paths: [][]Point{best.Path}, Meters: alternativeCorridorMeters}
+	for i, alternative := range alternatives {
+		if overlap := corridor.Overlap(alternative.Path); overlap > maxAlternativeOverlap {
+			t.Errorf("alternative %d lies %.0f%% inside the corridor of the best route", i+1, overlap*100)
+		}
+		if alternative.DistanceMeters < best.DistanceMeters {
+			t.Errorf("alternative %d of %.0f m is shorter than the best route of %.0f m", i+1, alternative.DistanceMeters, best.DistanceMeters)
+		}
+	}
+}
+
+func TestAlternativeSummaryZeroLengthBestRoute(t *testing.T) {
+	best := RoutePlan{Path: []Point{{X: 5, Y: 52}, {X: 5, Y: 52}}}
+	plan := RoutePlan{DistanceMeters: 1200}
+	if summary := alternativeSummary(best, plan, 1); strings.Contains(summary, "NaN") || strings.Contains(summary, "Inf") {
+		t.Errorf("summary %q", summary)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AltitudeBand limits the altitudes a route may use, in meters above ground level
+// A Max of 0 means no upper limit
+type AltitudeBand struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// Contains reports whether an altitude lies within the band
+func (b AltitudeBand) Contains(altitude float64) bool {
+	return altitude >= b.Min && (b.Max == 0 || altitude <= b.Max)
+}
+
+// parseAltitudeLayers parses a comma-separated list of altitudes in meters
+// into a sorted list without duplicates
+func parseAltitudeLayers(value string) ([]float64, error) {
+	var layers []float64
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		altitude, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid altitude %q: %w", field, err)
+		}
+		layers = append(layers, altitude)
+	}
+
+	sort.Float64s(layers)
+	unique := layers[:0]
+	for i, altitude := range layers {
+		if i == 0 || altitude != layers[i-1] {
+			unique = append(unique, altitude)
+		}
+	}
+	return unique, nil
+}
+
+// sameAltitudeLayers reports whether two layer lists are equal, treating an
+// empty list as the single ground layer
+func sameAltitudeLayers(a, b []float64) bool {
+	if len(a) == 0 {
+		a = []float64{0}
+	}
+	if len(b) == 0 {
+		b = []float64{0}
+	}
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
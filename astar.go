@@ -47,13 +47,15 @@ func (pq *PriorityQueue) Pop() interface{} {
 }
 
 // AStarPathOnGraph computes the shortest path using A* on a visibility graph
-func AStarPathOnGraph(graph *Graph, startIdx, endIdx int) ([]Point, bool) {
-	if graph == nil || len(graph.Nodes) == 0 {
+// The graph's heuristic never overestimates the edge costs to the goal, so
+// the returned path is minimal
+func AStarPathOnGraph(graph SearchGraph, startIdx, endIdx int) ([]Point, bool) {
+	if graph == nil {
 		return []Point{}, false
 	}
 
-	startPoint := graph.Nodes[startIdx]
-	endPoint := graph.Nodes[endIdx]
+	startPoint := graph.NodePoint(startIdx)
+	endPoint := graph.NodePoint(endIdx)
 
 	openSet := &PriorityQueue{}
 	heap.Init(openSet)
@@ -61,8 +63,8 @@ func AStarPathOnGraph(graph *Graph, startIdx, endIdx int) ([]Point, bool) {
 	startNode := &Node{
 		NodeID: startIdx,
 		G:      0,
-		H:      startPoint.Distance(endPoint),
-		F:      startPoint.Distance(endPoint),
+		H:      graph.Heuristic(startPoint, endPoint),
+		F:      graph.Heuristic(startPoint, endPoint),
 	}
 	heap.Push(openSet, startNode)
 
@@ -82,7 +84,7 @@ func AStarPathOnGraph(graph *Graph, startIdx, endIdx int) ([]Point, bool) {
 			// Reconstruct path
 			path := []Point{}
 			for node := current; node != nil; node = node.Parent {
-				path = append([]Point{graph.Nodes[node.NodeID]}, path...)
+				path = append([]Point{graph.NodePoint(node.NodeID)}, path...)
 			}
 			return path, true
 		}
@@ -90,7 +92,7 @@ func AStarPathOnGraph(graph *Graph, startIdx, endIdx int) ([]Point, bool) {
 		closedSet[current.NodeID] = true
 
 		// Explore neighbors
-		for _, edge := range graph.Edges[current.NodeID] {
+		for _, edge := range graph.Neighbors(current.NodeID) {
 			neighborID := edge.To
 
 			if closedSet[neighborID] {
@@ -102,11 +104,11 @@ func AStarPathOnGraph(graph *Graph, startIdx, endIdx int) ([]Point, bool) {
 
 			neighbor, exists := openSetMap[neighborID]
 			if !exists {
-				neighborPoint := graph.Nodes[neighborID]
+				neighborPoint := graph.NodePoint(neighborID)
 				neighbor = &Node{
 					NodeID: neighborID,
 					G:      tentativeG,
-					H:      neighborPoint.Distance(endPoint),
+					H:      graph.Heuristic(neighborPoint, endPoint),
 					Parent: current,
 				}
 				neighbor.F = neighbor.G + neighbor.H
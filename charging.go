@@ -0,0 +1,322 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"time"
+)
+
+// ChargingSite is a location where a drone can land and recharge
+type ChargingSite struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name,omitempty"`
+	Point      Point                  `json:"point"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+// ChargingStop is a charging site a route lands at
+type ChargingStop struct {
+	ChargingSite
+	WaypointIndex int `json:"waypointIndex"` // Index of the site in the route path
+}
+
+// EnergyLeg is the energy used between two stops or charging sites
+type EnergyLeg struct {
+	From              string  `json:"from"`
+	To                string  `json:"to"`
+	DistanceMeters    float64 `json:"distanceMeters"`
+	AirDistanceMeters float64 `json:"airDistanceMeters"` // Distance in still air, the energy is based on
+	EnergyWh          float64 `json:"energyWh"`
+	RemainingWh       float64 `json:"remainingWh"` // Usable energy left on arrival
+	recharged         bool    // The battery is recharged before this leg
+}
+
+// EnergySummary is the battery use of a route
+type EnergySummary struct {
+	BatteryWh float64     `json:"batteryWh"`
+	UsableWh  float64     `json:"usableWh"` // Battery capacity minus the reserve
+	TotalWh   float64     `json:"totalWh"`  // Energy used over all legs
+	Legs      []EnergyLeg `json:"legs"`
+}
+
+// HasBattery reports whether the vehicle's range is limited by a battery
+func (v VehicleProfile) HasBattery() bool {
+	return v.BatteryWh > 0 && v.ConsumptionWhPerKm > 0
+}
+
+// UsableWh returns the battery energy that may be used per flight
+func (v VehicleProfile) UsableWh() float64 {
+	return v.BatteryWh * (1 - v.ReservePercent/100)
+}
+
+// RangeMeters returns the distance that can be flown on one charge, or
+// infinity without a battery model
+func (v VehicleProfile) RangeMeters() float64 {
+	if !v.HasBattery() {
+		return math.Inf(1)
+	}
+	return v.UsableWh() / v.ConsumptionWhPerKm * 1000
+}
+
+// InRange reports whether a still-air distance can be flown on one charge
+func (v VehicleProfile) InRange(distanceMeters float64) bool {
+	return distanceMeters <= v.RangeMeters()
+}
+
+// EnergySummary computes the energy used per leg and the energy left after
+// it, starting with a full battery that is recharged at charging sites
+func (v VehicleProfile) EnergySummary(legs []EnergyLeg) *EnergySummary {
+	summary := &EnergySummary{BatteryWh: v.BatteryWh, UsableWh: v.UsableWh(), Legs: legs}
+	battery := summary.UsableWh
+	for i := range legs {
+		if legs[i].recharged {
+			battery = summary.UsableWh
+		}
+		legs[i].EnergyWh = legs[i].AirDistanceMeters / 1000 * v.ConsumptionWhPerKm
+		battery -= legs[i].EnergyWh
+		legs[i].RemainingWh = battery
+		summary.TotalWh += legs[i].EnergyWh
+	}
+	return summary
+}
+
+// LoadChargingSites reads charging sites from the Point features of a GeoJSON FeatureCollection
+// The id and name properties are used when present
+func LoadChargingSites(filename string) ([]ChargingSite, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var featureCollection GeoJSONFeatureCollection
+	if err := json.Unmarshal(data, &featureCollection); err != nil {
+		return nil, fmt.Errorf("failed to parse charging sites: %w", err)
+	}
+
+	var sites []ChargingSite
+	for i, feature := range featureCollection.Features {
+		if feature.Geometry.Type != "Point" {
+			continue
+		}
+		var coords []float64
+		if err := json.Unmarshal(feature.Geometry.Coordinates, &coords); err != nil || len(coords) < 2 {
+			log.Printf("⚠️  Skipping charging site %d: invalid coordinates\n", i)
+			continue
+		}
+
+		site := ChargingSite{
+			ID:         fmt.Sprint(i),
+			Point:      Point{X: coords[0], Y: coords[1]},
+			Properties: feature.Properties,
+		}
+		if id, ok := feature.Properties["id"]; ok {
+			site.ID = fmt.Sprint(id)
+		}
+		if name, ok := feature.Properties["name"].(string); ok {
+			site.Name = name
+		}
+		sites = append(sites, site)
+	}
+
+	return sites, nil
+}
+
+// planWithChargingStops plans a route that lands at charging sites so that no
+// leg between landings exceeds the vehicle's range, minimizing the time spent
+// flying at cruise speed plus the time spent landing and recharging at every stop
+// The first leg may only use the range remaining when the route starts
+// Legs are planned lazily with Dijkstra over the start, the sites and the end,
+// only between stops whose straight-line distance is within range, allowing
+// for the strongest tailwind; each leg departs once the flights and charging
+// stops before it are done, so it avoids the temporary zones active by then
+func planWithChargingStops(graph *PRMGraph, start, end Point, opts PlanOptions, remaining float64, startName, endName string) RoutePlan {
+	rangeMeters := opts.Vehicle.RangeMeters()
+	if len(opts.ChargingSites) == 0 {
+		log.Println("❌ No charging sites loaded")
+		return RoutePlan{Message: fmt.Sprintf(
+			"Route exceeds the usable range of %.1f km and no charging sites are available", rangeMeters/1000)}
+	}
+	limit := func(from int) float64 {
+		if from == 0 {
+			return remaining
+		}
+		return rangeMeters
+	}
+
+	// Stop 0 is the start, the last stop the end, the others are charging sites
+	stops := make([]Point, 0, len(opts.ChargingSites)+2)
+	stops = append(stops, start)
+	for _, site := range opts.ChargingSites {
+		stops = append(stops, site.Point)
+	}
+	stops = append(stops, end)
+	endStop := len(stops) - 1
+
+	// dist[i] is the time in seconds from departure until the drone takes off
+	// again at stop i, final once Dijkstra has settled the stop
+	dist := make([]float64, len(stops))
+	legs := make(map[[2]int]RoutePlan)
+	planStopLeg := func(from, to int) (RoutePlan, bool) {
+		key := [2]int{from, to}
+		if leg, ok := legs[key]; ok {
+			return leg, leg.Success
+		}
+		legOpts := opts
+		legOpts.Departure = opts.Departure.Add(time.Duration(dist[from] * float64(time.Second)))
+		// The requested headings only apply at the start and end of the route
+		if opts.Kinematics != nil {
+			kinematics := *opts.Kinematics
+			if from != 0 {
+				kinematics.StartHeading = nil
+			}
+			if to != endStop {
+				kinematics.EndHeading = nil
+			}
+			legOpts.Kinematics = &kinematics
+		}
+		leg := planLeg(graph, stops[from], stops[to], legOpts)
+		if leg.Success && leg.AirMeters > limit(from) {
+			leg.Success = false
+		}
+		legs[key] = leg
+		return leg, leg.Success
+	}
+
+	stopSeconds := opts.Vehicle.LandingSeconds + opts.Vehicle.ChargingSeconds + opts.Vehicle.TakeoffSeconds
+	legSeconds := func(leg RoutePlan, to int) float64 {
+		seconds := leg.AirMeters / opts.Vehicle.CruiseSpeedMps
+		if to != endStop {
+			seconds += stopSeconds
+		}
+		return seconds
+	}
+
+	tailwindFactor := opts.Vehicle.CruiseSpeedMps / (opts.Vehicle.CruiseSpeedMps + opts.Wind.MaxSpeed())
+
+	prev := make([]int, len(stops))
+	done := make([]bool, len(stops))
+	for i := range dist {
+		dist[i] = math.Inf(1)
+		prev[i] = -1
+	}
+	dist[0] = 0
+
+	for {
+		current := -1
+		for i := range stops {
+			if !done[i] && !math.IsInf(dist[i], 1) && (current == -1 || dist[i] < dist[current]) {
+				current = i
+			}
+		}
+		if current == -1 || current == endStop {
+			break
+		}
+		done[current] = true
+
+		for next := range stops {
+			if done[next] || stops[current].HorizontalDistanceMeters(stops[next])*tailwindFactor > limit(current) {
+				continue
+			}
+			leg, ok := planStopLeg(current, next)
+			if ok && dist[current]+legSeconds(leg, next) < dist[next] {
+				dist[next] = dist[current] + legSeconds(leg, next)
+				prev[next] = current
+			}
+		}
+	}
+	log.Printf("   Planned %d candidate legs between %d charging sites\n", len(legs), len(opts.ChargingSites))
+
+	if math.IsInf(dist[endStop], 1) {
+		log.Println("❌ No chain of charging sites within range")
+		return RoutePlan{Message: fmt.Sprintf(
+			"Route exceeds the usable range of %.1f km and no chain of charging sites with legs within range connects start and end",
+			rangeMeters/1000)}
+	}
+
+	var order []int
+	for stop := endStop; stop != -1; stop = prev[stop] {
+		order = append([]int{stop}, order...)
+	}
+
+	name := func(stop int) string {
+		switch stop {
+		case 0:
+			return startName
+		case endStop:
+			return endName
+		}
+		return opts.ChargingSites[stop-1].ID
+	}
+
+	var plan RoutePlan
+	for i := 0; i < len(order)-1; i++ {
+		leg := legs[[2]int{order[i], order[i+1]}]
+		if i > 0 {
+			plan.ChargingStops = append(plan.ChargingStops, ChargingStop{
+				ChargingSite:  opts.ChargingSites[order[i]-1],
+				WaypointIndex: len(plan.Path) - 1,
+			})
+		}
+		plan = plan.appendLeg(leg)
+		plan.energyLegs = append(plan.energyLegs, EnergyLeg{
+			From:              name(order[i]),
+			To:                name(order[i+1]),
+			DistanceMeters:    leg.FlownMeters(),
+			AirDistanceMeters: leg.AirMeters,
+			recharged:         i > 0,
+		})
+	}
+
+	log.Printf("   ✅ %d charging stops, %.2f km in total\n", len(plan.ChargingStops), plan.FlownMeters()/1000)
+	return plan
+}
+
+// appendLeg stitches a planned leg that starts where the plan ends onto the plan
+func (plan RoutePlan) appendLeg(leg RoutePlan) RoutePlan {
+	if len(plan.Path) == 0 {
+		return leg
+	}
+
+	offset := len(plan.Path) - 1
+	distance := plan.DistanceMeters
+
+	plan.Path = append(plan.Path[:offset:offset], leg.Path...)
+	plan.Direct = false
+	plan.RawWaypoints += leg.RawWaypoints
+	plan.DistanceMeters += leg.DistanceMeters
+	plan.RestrictedDistanceMeters += leg.RestrictedDistanceMeters
+	plan.Cost += leg.Cost
+	plan.AirMeters += leg.AirMeters
+	plan.energyLegs = append(plan.energyLegs, leg.energyLegs...)
+	for _, stop := range leg.ChargingStops {
+		stop.WaypointIndex += offset
+		plan.ChargingStops = append(plan.ChargingStops, stop)
+	}
+	plan.ActiveTemporaryZones = max(plan.ActiveTemporaryZones, leg.ActiveTemporaryZones)
+	if leg.FlightWindow != nil && plan.FlightWindow != nil {
+		plan.FlightWindow = &TimeWindow{Start: plan.FlightWindow.Start, End: leg.FlightWindow.End}
+	}
+
+	if leg.AltitudeProfile != nil {
+		profile := append([]ProfilePoint(nil), plan.AltitudeProfile[:offset]...)
+		for _, point := range leg.AltitudeProfile {
+			point.DistanceMeters += distance
+			profile = append(profile, point)
+		}
+		plan.AltitudeProfile = profile
+	}
+
+	if leg.Trajectory != nil {
+		trajectoryOffset := len(plan.Trajectory) - 1
+		plan.Trajectory = append(plan.Trajectory[:trajectoryOffset:trajectoryOffset], leg.Trajectory...)
+		plan.TrajectoryMeters += leg.TrajectoryMeters
+		for _, index := range leg.trajectoryWaypoints[1:] {
+			plan.trajectoryWaypoints = append(plan.trajectoryWaypoints, index+trajectoryOffset)
+		}
+	}
+
+	return plan
+}
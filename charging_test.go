@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestChargingStopLegsDepartAfterCharging(t *testing.T) {
+	opts := testPlanOptions(nil, nil)
+	opts.Vehicle.BatteryWh = 150
+	opts.Vehicle.ConsumptionWhPerKm = 10
+	opts.Vehicle.ReservePercent = 0
+	opts.ChargingSites = []ChargingSite{{ID: "middle", Point: Point{X: 5.15, Y: 52.0}}}
+
+	// A temporary zone on the second leg that expires while the drone recharges
+	validFrom := opts.Departure
+	validTo := opts.Departure.Add(25 * time.Minute)
+	zone := squareZone(5.22, 51.99, 0.02)
+	zone.ValidFrom, zone.ValidTo = &validFrom, &validTo
+	opts.TemporaryZones = NewZoneIndex([]Polygon{zone})
+
+	start, end := Point{X: 5.0, Y: 52.0}, Point{X: 5.3, Y: 52.0}
+	plan := planWithChargingStops(nil, start, end, opts, opts.Vehicle.RangeMeters(), "start", "end")
+	if !plan.Success {
+		t.Fatalf("no route: %s", plan.Message)
+	}
+	if len(plan.ChargingStops) != 1 {
+		t.Fatalf("%d charging stops, want 1", len(plan.ChargingStops))
+	}
+	if plan.FlightWindow == nil || !plan.FlightWindow.End.After(validTo) {
+		t.Errorf("flight window %v does not end after the zone expires", plan.FlightWindow)
+	}
+}
+
+func TestChargingStopLegsKeepHeadingsAtRouteEnds(t *testing.T) {
+	opts := testPlanOptions(nil, nil)
+	opts.Vehicle.BatteryWh = 150
+	opts.Vehicle.ConsumptionWhPerKm = 10
+	opts.Vehicle.ReservePercent = 0
+	opts.ChargingSites = []ChargingSite{{ID: "middle", Point: Point{X: 5.15, Y: 52.0}}}
+	south, north := 180.0, 0.0
+	opts.Kinematics = &Kinematics{TurnRadius: 300, StartHeading: &south, EndHeading: &north}
+
+	start, site, end := Point{X: 5.0, Y: 52.0}, opts.ChargingSites[0].Point, Point{X: 5.3, Y: 52.0}
+	plan := planWithChargingStops(nil, start, end, opts, opts.Vehicle.RangeMeters(), "start", "end")
+	if !plan.Success || len(plan.ChargingStops) != 1 {
+		t.Fatalf("no route through the charging site: %s", plan.Message)
+	}
+
+	// The first leg keeps only the start heading and the second only the end heading
+	first, second := opts, opts
+	first.Kinematics = &Kinematics{TurnRadius: 300, StartHeading: &south}
+	second.Kinematics = &Kinematics{TurnRadius: 300, EndHeading: &north}
+	want := planLeg(nil, start, site, first).TrajectoryMeters + planLeg(nil, site, end, second).TrajectoryMeters
+	if math.Abs(plan.TrajectoryMeters-want) > 1 {
+		t.Errorf("trajectory of %.0f m, want %.0f m", plan.TrajectoryMeters, want)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import "math"
+
+// CostModel computes A* edge costs in meters, adding a penalty for every
+// meter flown inside a restricted zone
+// With a wind field the distance is replaced by the airtime expressed in
+// still-air meters, so A* minimizes the time in the air
+// Alternative routes add a penalty for every meter flown in the corridor of earlier routes
+type CostModel struct {
+	Restricted        *ZoneIndex // Zones that add a traversal penalty
+	RestrictedPenalty float64    // Extra cost per meter flown inside restricted zones
+	Wind              *WindField // Wind that changes the ground speed, nil for still air
+	Airspeed          float64    // Meters per second through the air, used with the wind
+	Avoid             *Corridor  // Corridor of earlier routes, nil for none
+	AvoidPenalty      float64    // Extra cost per meter flown inside the corridor
+}
+
+// EdgeCost returns the cost of flying a straight line between two points
+// The cost is never less than Heuristic, keeping A* admissible
+func (m *CostModel) EdgeCost(from, to Point) float64 {
+	cost := from.DistanceMeters(to)
+	if m.Wind != nil {
+		cost = math.Hypot(m.Wind.AirMeters(from, to, m.Airspeed), to.Z-from.Z)
+	}
+	if m.Restricted != nil && m.RestrictedPenalty > 0 {
+		cost += m.RestrictedPenalty * m.Restricted.LengthInsideZones(LineSegment{P1: from, P2: to})
+	}
+	if m.Avoid != nil && m.AvoidPenalty > 0 {
+		cost += m.AvoidPenalty * m.Avoid.LengthInside(from, to)
+	}
+	return cost
+}
+
+// Heuristic returns a lower bound of the cost between two points: the
+// great-circle distance, scaled down by the strongest possible tailwind
+func (m *CostModel) Heuristic(from, to Point) float64 {
+	distance := from.DistanceMeters(to)
+	if m.Wind != nil {
+		distance *= m.Airspeed / (m.Airspeed + m.Wind.MaxSpeed())
+	}
+	return distance
+}
+
+// PathCost returns the summed edge cost of a path
+func (m *CostModel) PathCost(path []Point) float64 {
+	cost := 0.0
+	for i := 0; i < len(path)-1; i++ {
+		cost += m.EdgeCost(path[i], path[i+1])
+	}
+	return cost
+}
+
+// RestrictedLengthMeters returns the length of a path flown inside restricted zones
+func (m *CostModel) RestrictedLengthMeters(path []Point) float64 {
+	if m.Restricted == nil {
+		return 0
+	}
+	length := 0.0
+	for i := 0; i < len(path)-1; i++ {
+		length += m.Restricted.LengthInsideZones(LineSegment{P1: path[i], P2: path[i+1]})
+	}
+	return length
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// Kinematics describes the turn constraints of a fixed-wing drone
+type Kinematics struct {
+	TurnRadius   float64  // Minimum turning radius in meters
+	StartHeading *float64 // Degrees clockwise from north, nil to follow the first leg
+	EndHeading   *float64 // Degrees clockwise from north, nil to follow the last leg
+}
+
+// maxTrajectoryStep is the largest distance in meters between trajectory samples
+const maxTrajectoryStep = 50.0
+
+// maxDubinsSubdivisions limits how often a leg is split when none of its
+// Dubins curves are clear of the no-fly zones
+const maxDubinsSubdivisions = 6
+
+// pose is a position in local meters with a heading in radians counterclockwise from east
+type pose struct {
+	X, Y, Theta float64
+}
+
+// dubinsPath is one of the six Dubins words between two poses, with the
+// segment lengths normalized by the turning radius
+type dubinsPath struct {
+	Word    string // Segment types, L (left), S (straight) or R (right)
+	Lengths [3]float64
+}
+
+// Length returns the normalized length of the path
+func (d dubinsPath) Length() float64 {
+	return d.Lengths[0] + d.Lengths[1] + d.Lengths[2]
+}
+
+// mod2pi wraps an angle to [0, 2π)
+func mod2pi(angle float64) float64 {
+	return angle - 2*math.Pi*math.Floor(angle/(2*math.Pi))
+}
+
+// dubinsPaths returns every feasible Dubins word between two poses, shortest first
+func dubinsPaths(from, to pose, radius float64) []dubinsPath {
+	dx, dy := to.X-from.X, to.Y-from.Y
+	d := math.Hypot(dx, dy) / radius
+	theta := mod2pi(math.Atan2(dy, dx))
+	alpha := mod2pi(from.Theta - theta)
+	beta := mod2pi(to.Theta - theta)
+
+	sa, sb := math.Sin(alpha), math.Sin(beta)
+	ca, cb := math.Cos(alpha), math.Cos(beta)
+	cab := math.Cos(alpha - beta)
+
+	var paths []dubinsPath
+	add := func(word string, t, p, q float64) {
+		paths = append(paths, dubinsPath{Word: word, Lengths: [3]float64{t, p, q}})
+	}
+
+	if pSq := 2 + d*d - 2*cab + 2*d*(sa-sb); pSq >= 0 {
+		tmp := math.Atan2(cb-ca, d+sa-sb)
+		add("LSL", mod2pi(tmp-alpha), math.Sqrt(pSq), mod2pi(beta-tmp))
+	}
+	if pSq := 2 + d*d - 2*cab + 2*d*(sb-sa); pSq >= 0 {
+		tmp := math.Atan2(ca-cb, d-sa+sb)
+		add("RSR", mod2pi(alpha-tmp), math.Sqrt(pSq), mod2pi(tmp-beta))
+	}
+	if pSq := -2 + d*d + 2*cab + 2*d*(sa+sb); pSq >= 0 {
+		p := math.Sqrt(pSq)
+		tmp := math.Atan2(-ca-cb, d+sa+sb) - math.Atan2(-2, p)
+		add("LSR", mod2pi(tmp-alpha), p, mod2pi(tmp-beta))
+	}
+	if pSq := -2 + d*d + 2*cab - 2*d*(sa+sb); pSq >= 0 {
+		p := math.Sqrt(pSq)
+		tmp := math.Atan2(ca+cb, d-sa-sb) - math.Atan2(2, p)
+		add("RSL", mod2pi(alpha-tmp), p, mod2pi(beta-tmp))
+	}
+	if tmp := (6 - d*d + 2*cab + 2*d*(sa-sb)) / 8; math.Abs(tmp) <= 1 {
+		phi := math.Atan2(ca-cb, d-sa+sb)
+		p := mod2pi(2*math.Pi - math.Acos(tmp))
+		t := mod2pi(alpha - phi + mod2pi(p/2))
+		add("RLR", t, p, mod2pi(alpha-beta-t+mod2pi(p)))
+	}
+	if tmp := (6 - d*d + 2*cab + 2*d*(sb-sa)) / 8; math.Abs(tmp) <= 1 {
+		phi := math.Atan2(ca-cb, d+sa-sb)
+		p := mod2pi(2*math.Pi - math.Acos(tmp))
+		t := mod2pi(-alpha - phi + p/2)
+		add("LRL", t, p, mod2pi(beta-alpha-t+mod2pi(p)))
+	}
+
+	sort.Slice(paths, func(i, j int) bool {
+		return paths[i].Length() < paths[j].Length()
+	})
+	return paths
+}
+
+// dubinsSegment moves a normalized pose along one segment of a Dubins word
+func dubinsSegment(start pose, kind byte, length float64) pose {
+	switch kind {
+	case 'L':
+		return pose{
+			X:     start.X + math.Sin(start.Theta+length) - math.Sin(start.Theta),
+			Y:     start.Y - math.Cos(start.Theta+length) + math.Cos(start.Theta),
+			Theta: start.Theta + length,
+		}
+	case 'R':
+		return pose{
+			X:     start.X - math.Sin(start.Theta-length) + math.Sin(start.Theta),
+			Y:     start.Y + math.Cos(start.Theta-length) - math.Cos(start.Theta),
+			Theta: start.Theta - length,
+		}
+	default:
+		return pose{
+			X:     start.X + math.Cos(start.Theta)*length,
+			Y:     start.Y + math.Sin(start.Theta)*length,
+			Theta: start.Theta,
+		}
+	}
+}
+
+// Sample returns poses along the path every step meters, including both ends
+func (d dubinsPath) Sample(from pose, radius, step float64) []pose {
+	total := d.Length() * radius
+	count := int(math.Ceil(total/step)) + 1
+
+	samples := make([]pose, 0, count+1)
+	for i := 0; i <= count; i++ {
+		// Walk the segments in normalized units up to the sample distance
+		remaining := total * float64(i) / float64(count) / radius
+		current := pose{Theta: from.Theta}
+		for s := 0; s < 3; s++ {
+			length := math.Min(remaining, d.Lengths[s])
+			current = dubinsSegment(current, d.Word[s], length)
+			remaining -= length
+		}
+		samples = append(samples, pose{
+			X:     from.X + current.X*radius,
+			Y:     from.Y + current.Y*radius,
+			Theta: current.Theta,
+		})
+	}
+	return samples
+}
+
+// localFrame converts between longitude/latitude and local meters around a reference latitude
+type localFrame struct {
+	refLat float64
+}
+
+func (f localFrame) toLocal(p Point) (float64, float64) {
+	return toLocalMeters(p, f.refLat)
+}
+
+func (f localFrame) toPoint(x, y, z float64) Point {
+	return Point{X: x / (metersPerDegree * math.Cos(f.refLat*math.Pi/180.0)), Y: y / metersPerDegree, Z: z}
+}
+
+// compassToTheta converts a heading in degrees clockwise from north to
+// radians counterclockwise from east
+func compassToTheta(heading float64) float64 {
+	return mod2pi(math.Pi/2 - heading*math.Pi/180.0)
+}
+
+// finalHeading returns the compass heading in degrees on arrival at the end of
+// a trajectory, falling back to the bearing of the last leg of the path it
+// follows when the trajectory has no segment of non-zero length
+// It reports false when neither has one
+func finalHeading(trajectory, path []Point) (float64, bool) {
+	for _, points := range [][]Point{trajectory, path} {
+		n := len(points)
+		for i := n - 2; i >= 0; i-- {
+			if points[i].X == points[n-1].X && points[i].Y == points[n-1].Y {
+				continue
+			}
+			frame := localFrame{refLat: points[n-1].Y}
+			x1, y1 := frame.toLocal(points[i])
+			x2, y2 := frame.toLocal(points[n-1])
+			return mod2pi(math.Pi/2-math.Atan2(y2-y1, x2-x1)) * 180.0 / math.Pi, true
+		}
+	}
+	return 0, false
+}
+
+// Trajectory connects the waypoints of a path with Dubins curves clear of
+// the zones and returns the densified flyable trajectory, along with the
+// index in the trajectory of every waypoint
+// Each waypoint is passed along the bisector of its incoming and outgoing legs;
+// legs without a clear curve are split at their midpoint, and false is
+// returned if they still cannot be connected
+func (k *Kinematics) Trajectory(path []Point, zones *ZoneIndex) ([]Point, []int, bool) {
+	if len(path) < 2 {
+		return path, []int{0}, true
+	}
+
+	refLat := 0.0
+	for _, p := range path {
+		refLat += p.Y
+	}
+	frame := localFrame{refLat: refLat / float64(len(path))}
+
+	poses := make([]pose, len(path))
+	for i, p := range path {
+		poses[i].X, poses[i].Y = frame.toLocal(p)
+	}
+	legTheta := func(i int) float64 {
+		return math.Atan2(poses[i+1].Y-poses[i].Y, poses[i+1].X-poses[i].X)
+	}
+	for i := range poses {
+		switch {
+		case i == 0 && k.StartHeading != nil:
+			poses[i].Theta = compassToTheta(*k.StartHeading)
+		case i == len(poses)-1 && k.EndHeading != nil:
+			poses[i].Theta = compassToTheta(*k.EndHeading)
+		case i == 0:
+			poses[i].Theta = legTheta(0)
+		case i == len(poses)-1:
+			poses[i].Theta = legTheta(i - 1)
+		default:
+			in, out := legTheta(i-1), legTheta(i)
+			poses[i].Theta = math.Atan2(math.Sin(in)+math.Sin(out), math.Cos(in)+math.Cos(out))
+		}
+	}
+
+	step := math.Min(maxTrajectoryStep, k.TurnRadius/4)
+	trajectory := []Point{path[0]}
+	waypoints := []int{0}
+	for i := 0; i < len(path)-1; i++ {
+		leg, ok := k.connect(poses[i], poses[i+1], path[i].Z, path[i+1].Z, frame, step, zones, maxDubinsSubdivisions)
+		if !ok {
+			return nil, nil, false
+		}
+		trajectory = append(trajectory, leg[1:]...)
+		waypoints = append(waypoints, len(trajectory)-1)
+	}
+	return trajectory, waypoints, true
+}
+
+// connect returns the shortest clear Dubins curve between two poses as points,
+// splitting the leg at its midpoint when no curve is clear
+func (k *Kinematics) connect(from, to pose, fromZ, toZ float64, frame localFrame, step float64, zones *ZoneIndex, depth int) ([]Point, bool) {
+	for _, candidate := range dubinsPaths(from, to, k.TurnRadius) {
+		samples := candidate.Sample(from, k.TurnRadius, step)
+		points := make([]Point, len(samples))
+		for i, s := range samples {
+			z := fromZ + (toZ-fromZ)*float64(i)/float64(len(samples)-1)
+			points[i] = frame.toPoint(s.X, s.Y, z)
+		}
+
+		clear := true
+		for i := 0; i < len(points)-1 && clear; i++ {
+			clear = IsPathClear(points[i], points[i+1], zones)
+		}
+		if clear {
+			return points, true
+		}
+	}
+
+	if depth == 0 {
+		return nil, false
+	}
+
+	// Pass through the middle of the straight leg, heading along it
+	mid := pose{
+		X:     (from.X + to.X) / 2,
+		Y:     (from.Y + to.Y) / 2,
+		Theta: math.Atan2(to.Y-from.Y, to.X-from.X),
+	}
+	midZ := (fromZ + toZ) / 2
+	first, ok := k.connect(from, mid, fromZ, midZ, frame, step, zones, depth-1)
+	if !ok {
+		return nil, false
+	}
+	second, ok := k.connect(mid, to, midZ, toZ, frame, step, zones, depth-1)
+	if !ok {
+		return nil, false
+	}
+	return append(first, second[1:]...), true
+}
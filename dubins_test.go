@@ -0,0 +1,96 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestFinalHeading(t *testing.T) {
+	north := []Point{{X: 5, Y: 52}, {X: 5, Y: 52.01}}
+	east := []Point{{X: 5, Y: 52}, {X: 5.01, Y: 52}}
+	tests := []struct {
+		name       string
+		trajectory []Point
+		path       []Point
+		want       float64
+		wantOK     bool
+	}{
+		{"trajectory", north, east, 0, true},
+		{"repeated end point", append(append([]Point(nil), east...), east[1]), north, 90, true},
+		{"single point falls back to path", north[:1], east, 90, true},
+		{"empty trajectory falls back to path", nil, north, 0, true},
+		{"no segment", east[:1], []Point{east[0], east[0]}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := finalHeading(tt.trajectory, tt.path)
+			if ok != tt.wantOK || math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("finalHeading = %v, %v; want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDubinsPaths(t *testing.T) {
+	const radius = 100.0
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 200; n++ {
+		from := pose{X: rng.Float64()*1000 - 500, Y: rng.Float64()*1000 - 500, Theta: rng.Float64() * 2 * math.Pi}
+		to := pose{X: rng.Float64()*1000 - 500, Y: rng.Float64()*1000 - 500, Theta: rng.Float64() * 2 * math.Pi}
+		paths := dubinsPaths(from, to, radius)
+		if len(paths) == 0 {
+			t.Fatalf("no Dubins path from %+v to %+v", from, to)
+		}
+
+		straight := math.Hypot(to.X-from.X, to.Y-from.Y)
+		for i, path := range paths {
+			if i > 0 && path.Length() < paths[i-1].Length() {
+				t.Fatalf("%s is shorter than %s before it", path.Word, paths[i-1].Word)
+			}
+			if path.Length()*radius < straight-1e-6 {
+				t.Fatalf("%s of %.1f m is shorter than the straight line of %.1f m", path.Word, path.Length()*radius, straight)
+			}
+
+			samples := path.Sample(from, radius, 10)
+			end := samples[len(samples)-1]
+			headingError := math.Abs(math.Remainder(end.Theta-to.Theta, 2*math.Pi))
+			if math.Hypot(end.X-to.X, end.Y-to.Y) > 1e-6 || headingError > 1e-6 {
+				t.Fatalf("%s from %+v ends at %+v, want %+v", path.Word, from, end, to)
+			}
+		}
+	}
+}
+
+func TestTrajectoryHeadings(t *testing.T) {
+	south := 180.0
+	kinematics := &Kinematics{TurnRadius: 150, EndHeading: &south}
+	path := []Point{{X: 5, Y: 52, Z: 60}, {X: 5.02, Y: 52, Z: 60}, {X: 5.02, Y: 52.02, Z: 60}}
+
+	trajectory, waypoints, ok := kinematics.Trajectory(path, NewZoneIndex(nil))
+	if !ok {
+		t.Fatal("no trajectory without zones")
+	}
+	if len(waypoints) != len(path) {
+		t.Fatalf("%d waypoint indices for %d waypoints", len(waypoints), len(path))
+	}
+	for i, index := range waypoints {
+		if trajectory[index].HorizontalDistanceMeters(path[i]) > 0.01 {
+			t.Errorf("waypoint %d at %v, want %v", i, trajectory[index], path[i])
+		}
+	}
+
+	// The last sample chord may cut a turn by up to half its arc angle
+	tolerance := math.Min(maxTrajectoryStep, kinematics.TurnRadius/4) / (2 * kinematics.TurnRadius) * 180 / math.Pi
+	heading, _ := finalHeading(trajectory, path)
+	if math.Abs(math.Remainder(heading-south, 360)) > tolerance+0.1 {
+		t.Errorf("arrives with heading %.1f, want %.1f", heading, south)
+	}
+	length := 0.0
+	for i := 0; i < len(trajectory)-1; i++ {
+		length += trajectory[i].DistanceMeters(trajectory[i+1])
+	}
+	if direct := path[0].DistanceMeters(path[1]) + path[1].DistanceMeters(path[2]); length <= direct {
+		t.Errorf("trajectory of %.0f m is not longer than the %.0f m path with a turn", length, direct)
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// routeExportFormats maps the formats /route can export to their content types
+var routeExportFormats = map[string]string{
+	"geojson":   "application/geo+json",
+	"kml":       "application/vnd.google-earth.kml+xml",
+	"gpx":       "application/gpx+xml",
+	"plan":      "application/json",
+	"waypoints": "text/plain",
+}
+
+// writeRouteExport writes a planned route in one of the export formats
+func writeRouteExport(w http.ResponseWriter, format string, response RouteResponse, opts PlanOptions) {
+	var data []byte
+	var err error
+	switch format {
+	case "plan", "waypoints":
+		mission, missionErr := NewMission(response, opts)
+		if missionErr != nil {
+			log.Printf("❌ Mission not exported: %v\n", missionErr)
+			http.Error(w, "Mission not exported: "+missionErr.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if format == "plan" {
+			data, err = mission.QGCPlanJSON()
+		} else {
+			data = []byte(mission.WaypointFile())
+		}
+	case "geojson":
+		data, err = json.Marshal(routeGeoJSON(response))
+	case "kml":
+		data, err = xml.MarshalIndent(routeKML(response), "", "  ")
+	case "gpx":
+		data, err = xml.MarshalIndent(routeGPX(response), "", "  ")
+	}
+	if err != nil {
+		log.Printf("❌ Failed to export route as %s: %v\n", format, err)
+		http.Error(w, "Failed to export route", http.StatusInternalServerError)
+		return
+	}
+	if format == "kml" || format == "gpx" {
+		data = append([]byte(xml.Header), data...)
+	}
+
+	log.Printf("📤 Exported route as %s (%d bytes)\n", strings.ToUpper(format), len(data))
+	w.Header().Set("Content-Type", routeExportFormats[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=route.%s", format))
+	w.Write(data)
+}
+
+// routeAltitudes returns the flight altitude of every waypoint of a path and
+// whether it is above sea level, from the altitude profile over the terrain,
+// or the waypoint altitude above the ground without terrain
+func routeAltitudes(path []Point, profile []ProfilePoint) ([]float64, bool) {
+	altitudes := make([]float64, len(path))
+	for i, p := range path {
+		altitudes[i] = p.Z
+		if profile != nil {
+			altitudes[i] = profile[i].AltitudeMeters
+		}
+	}
+	return altitudes, profile != nil
+}
+
+// routeStopNames names the waypoints of a route that are requested stops or charging stops
+func routeStopNames(response RouteResponse) map[int]string {
+	names := map[int]string{0: "Start", len(response.Path) - 1: "End"}
+	for i, index := range response.StopIndexes {
+		if i > 0 && i < len(response.StopIndexes)-1 {
+			names[index] = fmt.Sprintf("Via %d", i)
+		}
+	}
+	for _, stop := range response.ChargingStops {
+		names[stop.WaypointIndex] = "Charging " + chargingSiteName(stop.ChargingSite)
+	}
+	return names
+}
+
+// chargingSiteName returns the name of a charging site, or its ID without one
+func chargingSiteName(site ChargingSite) string {
+	if site.Name != "" {
+		return site.Name
+	}
+	return site.ID
+}
+
+// routeDescription summarizes a route in one line
+func routeDescription(response RouteResponse) string {
+	parts := []string{fmt.Sprintf("%.1f km", response.DistanceMeters/1000)}
+	if response.Timing != nil {
+		parts = append(parts, fmt.Sprintf("%.0f min, ETA %s", response.Timing.TotalSeconds/60, response.Timing.ETA.Format(time.RFC3339)))
+	}
+	if len(response.ZonesAvoided) > 0 {
+		parts = append(parts, fmt.Sprintf("avoids %d no-fly zones", len(response.ZonesAvoided)))
+	}
+	if len(response.ChargingStops) > 0 {
+		parts = append(parts, fmt.Sprintf("%d charging stops", len(response.ChargingStops)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// lineStringFeature returns a GeoJSON LineString feature through the points
+func lineStringFeature(path []Point, altitudes []float64, properties map[string]interface{}) GeoJSONFeature {
+	coordinates := make([][]float64, len(path))
+	for i, p := range path {
+		coordinates[i] = []float64{p.X, p.Y, altitudes[i]}
+	}
+	data, _ := json.Marshal(coordinates)
+	return GeoJSONFeature{
+		Type:       "Feature",
+		Geometry:   GeoJSONGeometry{Type: "LineString", Coordinates: data},
+		Properties: properties,
+	}
+}
+
+// routeGeoJSON returns the route as a GeoJSON LineString feature, or as a
+// FeatureCollection starting with the route when there are alternatives
+func routeGeoJSON(response RouteResponse) interface{} {
+	altitudes, aboveSeaLevel := routeAltitudes(response.Path, response.AltitudeProfile)
+	reference := "agl"
+	if aboveSeaLevel {
+		reference = "msl"
+	}
+	properties := map[string]interface{}{
+		"name":                     "Route",
+		"description":              routeDescription(response),
+		"distanceMeters":           response.DistanceMeters,
+		"restrictedDistanceMeters": response.RestrictedDistanceMeters,
+		"zonesAvoided":             response.ZonesAvoided,
+		"altitudeReference":        reference,
+	}
+	if response.Timing != nil {
+		properties["vehicle"] = response.Timing.Vehicle
+		properties["departureTime"] = response.Timing.DepartureTime
+		properties["eta"] = response.Timing.ETA
+		properties["flightTimeSeconds"] = response.Timing.TotalSeconds
+		properties["waypointSeconds"] = response.Timing.WaypointSeconds
+	}
+	if response.StopIndexes != nil {
+		properties["stopIndexes"] = response.StopIndexes
+	}
+	if len(response.ChargingStops) > 0 {
+		properties["chargingStops"] = response.ChargingStops
+	}
+	if response.ActiveTemporaryZones > 0 {
+		properties["activeTemporaryZones"] = response.ActiveTemporaryZones
+	}
+
+	route := lineStringFeature(response.Path, altitudes, properties)
+	if len(response.Alternatives) == 0 {
+		return route
+	}
+
+	collection := GeoJSONFeatureCollection{Type: "FeatureCollection", Features: []GeoJSONFeature{route}}
+	for i, alternative := range response.Alternatives {
+		altitudes, _ := routeAltitudes(alternative.Path, alternative.AltitudeProfile)
+		properties := map[string]interface{}{
+			"name":              fmt.Sprintf("Alternative %d", i+1),
+			"description":       alternative.Summary,
+			"distanceMeters":    alternative.DistanceMeters,
+			"overlap":           alternative.Overlap,
+			"altitudeReference": reference,
+		}
+		if alternative.Timing != nil {
+			properties["flightTimeSeconds"] = alternative.Timing.TotalSeconds
+		}
+		collection.Features = append(collection.Features, lineStringFeature(alternative.Path, altitudes, properties))
+	}
+	return collection
+}
+
+// KML document structure for Google Earth
+type kmlDocument struct {
+	XMLName    xml.Name       `xml:"kml"`
+	Namespace  string         `xml:"xmlns,attr"`
+	Name       string         `xml:"Document>name"`
+	Placemarks []kmlPlacemark `xml:"Document>Placemark"`
+}
+
+type kmlPlacemark struct {
+	Name        string       `xml:"name"`
+	Description string       `xml:"description,omitempty"`
+	LineString  *kmlGeometry `xml:"LineString,omitempty"`
+	Point       *kmlGeometry `xml:"Point,omitempty"`
+}
+
+type kmlGeometry struct {
+	AltitudeMode string `xml:"altitudeMode"`
+	Coordinates  string `xml:"coordinates"`
+}
+
+// kmlCoordinates formats points as a KML coordinate list
+func kmlCoordinates(path []Point, altitudes []float64) string {
+	coordinates := make([]string, len(path))
+	for i, p := range path {
+		coordinates[i] = fmt.Sprintf("%.7f,%.7f,%.1f", p.X, p.Y, altitudes[i])
+	}
+	return strings.Join(coordinates, " ")
+}
+
+// routeKML returns the route, its alternatives and its stops as a KML document
+func routeKML(response RouteResponse) kmlDocument {
+	altitudes, aboveSeaLevel := routeAltitudes(response.Path, response.AltitudeProfile)
+	mode := "relativeToGround"
+	if aboveSeaLevel {
+		mode = "absolute"
+	}
+
+	doc := kmlDocument{Namespace: "http://www.opengis.net/kml/2.2", Name: "Drone route"}
+	doc.Placemarks = append(doc.Placemarks, kmlPlacemark{
+		Name:        "Route",
+		Description: routeDescription(response),
+		LineString:  &kmlGeometry{AltitudeMode: mode, Coordinates: kmlCoordinates(response.Path, altitudes)},
+	})
+
+	for i, alternative := range response.Alternatives {
+		altitudes, _ := routeAltitudes(alternative.Path, alternative.AltitudeProfile)
+		doc.Placemarks = append(doc.Placemarks, kmlPlacemark{
+			Name:        fmt.Sprintf("Alternative %d", i+1),
+			Description: alternative.Summary,
+			LineString:  &kmlGeometry{AltitudeMode: mode, Coordinates: kmlCoordinates(alternative.Path, altitudes)},
+		})
+	}
+
+	names := routeStopNames(response)
+	for i, p := range response.Path {
+		if name, ok := names[i]; ok {
+			doc.Placemarks = append(doc.Placemarks, kmlPlacemark{
+				Name:  name,
+				Point: &kmlGeometry{AltitudeMode: mode, Coordinates: kmlCoordinates([]Point{p}, altitudes[i:i+1])},
+			})
+		}
+	}
+	return doc
+}
+
+// GPX document structure for ground-station software
+type gpxDocument struct {
+	XMLName   xml.Name   `xml:"gpx"`
+	Version   string     `xml:"version,attr"`
+	Creator   string     `xml:"creator,attr"`
+	Namespace string     `xml:"xmlns,attr"`
+	Waypoints []gpxPoint `xml:"wpt"`
+	Routes    []gpxRoute `xml:"rte"`
+	Tracks    []gpxTrack `xml:"trk"`
+}
+
+type gpxPoint struct {
+	Lat       float64    `xml:"lat,attr"`
+	Lon       float64    `xml:"lon,attr"`
+	Elevation float64    `xml:"ele"`
+	Time      *time.Time `xml:"time,omitempty"`
+	Name      string     `xml:"name,omitempty"`
+}
+
+type gpxRoute struct {
+	Name        string     `xml:"name"`
+	Description string     `xml:"desc,omitempty"`
+	Points      []gpxPoint `xml:"rtept"`
+}
+
+type gpxTrack struct {
+	Name     string            `xml:"name"`
+	Segments []gpxTrackSegment `xml:"trkseg"`
+}
+
+type gpxTrackSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+// routeGPX returns the route as a GPX route of its waypoints and a track with
+// the estimated time at every waypoint; charging stops are added as waypoints
+// and alternatives as further routes
+// GPX elevations are the flight altitudes, above sea level only with terrain loaded
+func routeGPX(response RouteResponse) gpxDocument {
+	altitudes, _ := routeAltitudes(response.Path, response.AltitudeProfile)
+	names := routeStopNames(response)
+
+	doc := gpxDocument{Version: "1.1", Creator: "motion-planner", Namespace: "http://www.topografix.com/GPX/1/1"}
+	route := gpxRoute{Name: "Route", Description: routeDescription(response)}
+	track := gpxTrackSegment{}
+	for i, p := range response.Path {
+		point := gpxPoint{Lat: p.Y, Lon: p.X, Elevation: altitudes[i], Name: names[i]}
+		route.Points = append(route.Points, point)
+
+		if response.Timing != nil {
+			at := response.Timing.DepartureTime.Add(time.Duration(response.Timing.WaypointSeconds[i] * float64(time.Second))).Round(time.Second)
+			point.Time = &at
+		}
+		point.Name = ""
+		track.Points = append(track.Points, point)
+	}
+	doc.Routes = append(doc.Routes, route)
+	doc.Tracks = append(doc.Tracks, gpxTrack{Name: "Route", Segments: []gpxTrackSegment{track}})
+
+	for _, stop := range response.ChargingStops {
+		doc.Waypoints = append(doc.Waypoints, gpxPoint{
+			Lat:       stop.Point.Y,
+			Lon:       stop.Point.X,
+			Elevation: altitudes[stop.WaypointIndex],
+			Name:      "Charging " + chargingSiteName(stop.ChargingSite),
+		})
+	}
+
+	for i, alternative := range response.Alternatives {
+		route := gpxRoute{Name: fmt.Sprintf("Alternative %d", i+1), Description: alternative.Summary}
+		altitudes, _ := routeAltitudes(alternative.Path, alternative.AltitudeProfile)
+		for j, p := range alternative.Path {
+			route.Points = append(route.Points, gpxPoint{Lat: p.Y, Lon: p.X, Elevation: altitudes[j]})
+		}
+		doc.Routes = append(doc.Routes, route)
+	}
+	return doc
+}
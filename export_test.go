@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestRouteKMLAlternativeAltitudes(t *testing.T) {
+	path := []Point{{X: 5.0, Y: 52.0, Z: 40}, {X: 5.1, Y: 52.1, Z: 40}}
+	profile := []ProfilePoint{{AltitudeMeters: 50}, {AltitudeMeters: 90}}
+	alternative := AlternativeRoute{
+		Path:            []Point{{X: 5.0, Y: 52.0, Z: 40}, {X: 5.1, Y: 52.0, Z: 40}},
+		AltitudeProfile: []ProfilePoint{{AltitudeMeters: 50}, {AltitudeMeters: 70}},
+	}
+	response := RouteResponse{Path: path, AltitudeProfile: profile, Alternatives: []AlternativeRoute{alternative}}
+
+	doc := routeKML(response)
+	route, other := doc.Placemarks[0].LineString, doc.Placemarks[1].LineString
+	if route.AltitudeMode != "absolute" || other.AltitudeMode != "absolute" {
+		t.Errorf("altitude modes %q and %q, want absolute", route.AltitudeMode, other.AltitudeMode)
+	}
+	if want := "5.0000000,52.0000000,50.0 5.1000000,52.0000000,70.0"; other.Coordinates != want {
+		t.Errorf("alternative coordinates %q, want %q", other.Coordinates, want)
+	}
+
+	collection := routeGeoJSON(response).(GeoJSONFeatureCollection)
+	for _, feature := range collection.Features {
+		if reference := feature.Properties["altitudeReference"]; reference != "msl" {
+			t.Errorf("%v: altitudeReference %v, want msl", feature.Properties["name"], reference)
+		}
+	}
+}
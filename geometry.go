@@ -1,10 +1,60 @@
 package main
 
-import "math"
+import (
+	"math"
+	"sort"
+	"time"
+)
 
-// Polygon represents a no-fly zone as a list of vertices
+// Polygon represents a no-fly zone as an outer ring of vertices with optional
+// interior rings (holes). The interior of a hole is free space.
+// The zone applies between its Floor and Ceiling altitudes.
 type Polygon struct {
-	Vertices []Point `json:"vertices"`
+	Vertices []Point   `json:"vertices"`
+	Holes    [][]Point `json:"holes,omitempty"`
+	Buffer   float64   `json:"buffer,omitempty"`  // Safety clearance around the zone in meters
+	Floor    float64   `json:"floor,omitempty"`   // Lower vertical limit in meters above ground level
+	Ceiling  float64   `json:"ceiling,omitempty"` // Upper vertical limit in meters above ground level, 0 for unlimited
+
+	// Zone metadata from the GeoJSON feature properties
+	Category   ZoneCategory           `json:"category"`
+	GID        int                    `json:"gid,omitempty"`
+	LocalType  string                 `json:"localtype,omitempty"`
+	SourceTxt  string                 `json:"sourceTxt,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+
+	// Validity window of temporary zones, nil for permanent zones
+	ValidFrom *time.Time `json:"validFrom,omitempty"`
+	ValidTo   *time.Time `json:"validTo,omitempty"`
+}
+
+// AppliesBetween reports whether the zone's vertical limits overlap the altitude range [low, high]
+func (polygon Polygon) AppliesBetween(low, high float64) bool {
+	if high < polygon.Floor {
+		return false
+	}
+	if polygon.Ceiling > 0 && low >= polygon.Ceiling {
+		return false
+	}
+	return true
+}
+
+// IsActiveDuring reports whether the zone is in force at any moment between start and end
+func (polygon Polygon) IsActiveDuring(start, end time.Time) bool {
+	if polygon.ValidFrom != nil && polygon.ValidFrom.After(end) {
+		return false
+	}
+	if polygon.ValidTo != nil && polygon.ValidTo.Before(start) {
+		return false
+	}
+	return true
+}
+
+// rings returns the outer ring followed by all interior rings
+func (polygon Polygon) rings() [][]Point {
+	rings := make([][]Point, 0, len(polygon.Holes)+1)
+	rings = append(rings, polygon.Vertices)
+	return append(rings, polygon.Holes...)
 }
 
 // Distance calculates Euclidean distance between two points
@@ -14,9 +64,19 @@ func (p Point) Distance(other Point) float64 {
 	return math.Sqrt(dx*dx + dy*dy)
 }
 
-// DistanceMeters calculates the distance in meters between two points in lat/lng coordinates
-// Uses the Haversine formula for accurate distance calculation
+// DistanceMeters calculates the distance in meters between two points in lat/lng coordinates,
+// including the altitude difference
 func (p Point) DistanceMeters(other Point) float64 {
+	horizontal := p.HorizontalDistanceMeters(other)
+	if p.Z == other.Z {
+		return horizontal
+	}
+	return math.Hypot(horizontal, other.Z-p.Z)
+}
+
+// HorizontalDistanceMeters calculates the ground distance in meters between two points
+// Uses the Haversine formula for accurate distance calculation
+func (p Point) HorizontalDistanceMeters(other Point) float64 {
 	const earthRadiusMeters = 6371000.0 // Earth's radius in meters
 
 	// Convert degrees to radians
@@ -34,6 +94,47 @@ func (p Point) DistanceMeters(other Point) float64 {
 	return earthRadiusMeters * c
 }
 
+// metersPerDegree is the approximate length of one degree of latitude
+const metersPerDegree = 111320.0
+
+// toLocalMeters projects a point onto a local planar frame in meters,
+// using an equirectangular projection around the reference latitude
+func toLocalMeters(p Point, refLat float64) (float64, float64) {
+	return p.X * metersPerDegree * math.Cos(refLat*math.Pi/180.0), p.Y * metersPerDegree
+}
+
+// metersToDegrees converts a distance in meters to a conservative margin in
+// degrees valid for both axes at the given latitude
+func metersToDegrees(meters, lat float64) float64 {
+	return meters / (metersPerDegree * math.Cos(math.Abs(lat)*math.Pi/180.0))
+}
+
+// pointSegmentDistanceMeters returns the distance in meters from a point to a line segment
+func pointSegmentDistanceMeters(p Point, seg LineSegment) float64 {
+	refLat := (p.Y + seg.P1.Y + seg.P2.Y) / 3
+	px, py := toLocalMeters(p, refLat)
+	ax, ay := toLocalMeters(seg.P1, refLat)
+	bx, by := toLocalMeters(seg.P2, refLat)
+
+	dx, dy := bx-ax, by-ay
+	t := 0.0
+	if lengthSq := dx*dx + dy*dy; lengthSq > 0 {
+		t = math.Max(0, math.Min(1, ((px-ax)*dx+(py-ay)*dy)/lengthSq))
+	}
+	return math.Hypot(px-(ax+t*dx), py-(ay+t*dy))
+}
+
+// segmentDistanceMeters returns the minimum distance in meters between two line segments
+func segmentDistanceMeters(seg1, seg2 LineSegment) float64 {
+	if DoSegmentsIntersect(seg1, seg2) {
+		return 0
+	}
+	return math.Min(
+		math.Min(pointSegmentDistanceMeters(seg1.P1, seg2), pointSegmentDistanceMeters(seg1.P2, seg2)),
+		math.Min(pointSegmentDistanceMeters(seg2.P1, seg1), pointSegmentDistanceMeters(seg2.P2, seg1)),
+	)
+}
+
 // LineSegment represents a line segment between two points
 type LineSegment struct {
 	P1, P2 Point
@@ -91,16 +192,32 @@ func onSegment(p, r, q Point) bool {
 }
 
 // IsPointInPolygon checks if a point is inside a polygon using ray casting
+// Points inside one of the polygon's holes are considered outside
 func IsPointInPolygon(point Point, polygon Polygon) bool {
-	n := len(polygon.Vertices)
+	if !isPointInRing(point, polygon.Vertices) {
+		return false
+	}
+
+	for _, hole := range polygon.Holes {
+		if isPointInRing(point, hole) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isPointInRing checks if a point is inside a single closed ring using ray casting
+func isPointInRing(point Point, ring []Point) bool {
+	n := len(ring)
 	if n < 3 {
 		return false
 	}
 
 	count := 0
 	for i := 0; i < n; i++ {
-		v1 := polygon.Vertices[i]
-		v2 := polygon.Vertices[(i+1)%n]
+		v1 := ring[i]
+		v2 := ring[(i+1)%n]
 
 		// Check if the ray from point to the right intersects the edge
 		if (v1.Y > point.Y) != (v2.Y > point.Y) {
@@ -120,45 +237,130 @@ func IsPointInPolygon(point Point, polygon Polygon) bool {
 	return count%2 == 1
 }
 
-// DoesSegmentIntersectPolygon checks if a line segment intersects any edge of a polygon
+// DoesSegmentIntersectPolygon checks if a line segment intersects any edge of a polygon,
+// including the edges of its holes
 func DoesSegmentIntersectPolygon(seg LineSegment, polygon Polygon) bool {
-	n := len(polygon.Vertices)
-	for i := 0; i < n; i++ {
-		edge := LineSegment{
-			P1: polygon.Vertices[i],
-			P2: polygon.Vertices[(i+1)%n],
+	for _, ring := range polygon.rings() {
+		n := len(ring)
+		for i := 0; i < n; i++ {
+			edge := LineSegment{
+				P1: ring[i],
+				P2: ring[(i+1)%n],
+			}
+			if DoSegmentsIntersect(seg, edge) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ringEdges returns the closed edges of all rings of a polygon
+func (polygon Polygon) ringEdges() []LineSegment {
+	var edges []LineSegment
+	for _, ring := range polygon.rings() {
+		n := len(ring)
+		for i := 0; i < n; i++ {
+			edges = append(edges, LineSegment{P1: ring[i], P2: ring[(i+1)%n]})
 		}
-		if DoSegmentsIntersect(seg, edge) {
+	}
+	return edges
+}
+
+// IsPointInBufferedPolygon checks if a point is inside a polygon or closer
+// than the polygon's safety buffer to any of its edges
+func IsPointInBufferedPolygon(point Point, polygon Polygon) bool {
+	if IsPointInPolygon(point, polygon) {
+		return true
+	}
+	if polygon.Buffer <= 0 {
+		return false
+	}
+	for _, edge := range polygon.ringEdges() {
+		if pointSegmentDistanceMeters(point, edge) < polygon.Buffer {
 			return true
 		}
 	}
 	return false
 }
 
-// IsPathClear checks if a straight line path between two points is collision-free
-func IsPathClear(p1, p2 Point, noFlyZones []Polygon) bool {
-	segment := LineSegment{P1: p1, P2: p2}
+// DoesSegmentIntersectBufferedPolygon checks if a line segment crosses a polygon
+// boundary or comes closer than the polygon's safety buffer to any of its edges
+func DoesSegmentIntersectBufferedPolygon(seg LineSegment, polygon Polygon) bool {
+	if polygon.Buffer <= 0 {
+		return DoesSegmentIntersectPolygon(seg, polygon)
+	}
+	for _, edge := range polygon.ringEdges() {
+		if segmentDistanceMeters(seg, edge) < polygon.Buffer {
+			return true
+		}
+	}
+	return false
+}
 
-	for _, zone := range noFlyZones {
-		// Check if the segment intersects the polygon boundary
-		if DoesSegmentIntersectPolygon(segment, zone) {
-			return false
+// segmentCrossings returns the positions (0..1) along seg where it crosses the
+// edges of the polygon's rings
+func segmentCrossings(seg LineSegment, polygon Polygon) []float64 {
+	var crossings []float64
+	rx, ry := seg.P2.X-seg.P1.X, seg.P2.Y-seg.P1.Y
+
+	for _, edge := range polygon.ringEdges() {
+		sx, sy := edge.P2.X-edge.P1.X, edge.P2.Y-edge.P1.Y
+		denom := rx*sy - ry*sx
+		if denom == 0 {
+			continue // Parallel or collinear
 		}
 
-		// Check if either endpoint is inside the polygon
-		if IsPointInPolygon(p1, zone) || IsPointInPolygon(p2, zone) {
-			return false
+		qx, qy := edge.P1.X-seg.P1.X, edge.P1.Y-seg.P1.Y
+		t := (qx*sy - qy*sx) / denom
+		u := (qx*ry - qy*rx) / denom
+		if t >= 0 && t <= 1 && u >= 0 && u <= 1 {
+			crossings = append(crossings, t)
 		}
+	}
+
+	return crossings
+}
+
+// SegmentLengthInsidePolygon returns the length in meters of the part of a
+// line segment that lies inside a polygon (holes excluded)
+func SegmentLengthInsidePolygon(seg LineSegment, polygon Polygon) float64 {
+	ts := append([]float64{0, 1}, segmentCrossings(seg, polygon)...)
+	sort.Float64s(ts)
 
-		// Check if the midpoint is inside (handles case where segment is entirely inside)
+	inside := 0.0
+	for i := 0; i < len(ts)-1; i++ {
+		if ts[i+1] == ts[i] {
+			continue
+		}
+		// Each piece between consecutive crossings is either fully inside or outside
+		mid := (ts[i] + ts[i+1]) / 2
 		midpoint := Point{
-			X: (p1.X + p2.X) / 2,
-			Y: (p1.Y + p2.Y) / 2,
+			X: seg.P1.X + mid*(seg.P2.X-seg.P1.X),
+			Y: seg.P1.Y + mid*(seg.P2.Y-seg.P1.Y),
 		}
-		if IsPointInPolygon(midpoint, zone) {
-			return false
+		if IsPointInPolygon(midpoint, polygon) {
+			inside += ts[i+1] - ts[i]
 		}
 	}
 
-	return true
+	return inside * seg.P1.DistanceMeters(seg.P2)
+}
+
+// IsPathClear checks if a straight line path between two points is collision-free,
+// keeping at least each zone's safety buffer as clearance
+// A climbing or descending segment is blocked by every zone whose vertical
+// limits overlap its altitude range anywhere along its footprint
+func IsPathClear(p1, p2 Point, zones *ZoneIndex) bool {
+	segment := LineSegment{P1: p1, P2: p2}
+
+	// Check if the segment intersects any polygon boundary
+	if zones.DoesSegmentIntersectZones(segment) {
+		return false
+	}
+
+	// Without boundary crossings the footprint lies entirely inside or outside
+	// every zone, so checking the start over the altitude range covers it all
+	low, high := segmentAltitudeRange(segment)
+	return !zones.isPointBlockedBetween(p1, low, high)
 }
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIsPathClear(t *testing.T) {
+	banded := squareZone(5.0, 52.0, 0.1)
+	banded.Holes = [][]Point{squareZone(5.04, 52.04, 0.02).Vertices}
+	banded.Floor = 60
+	banded.Ceiling = 120
+
+	buffered := squareZone(5.2, 52.0, 0.1)
+	buffered.Buffer = 200
+
+	thin := squareZone(5.4, 52.0, 0.1)
+	thin.Floor = 60
+	thin.Ceiling = 70
+
+	zones := NewZoneIndex([]Polygon{banded, buffered, thin})
+
+	tests := []struct {
+		name   string
+		p1, p2 Point
+		want   bool
+	}{
+		{"inside hole", Point{X: 5.045, Y: 52.045, Z: 80}, Point{X: 5.055, Y: 52.055, Z: 80}, true},
+		{"into hole from zone", Point{X: 5.02, Y: 52.05, Z: 80}, Point{X: 5.05, Y: 52.05, Z: 80}, false},
+		{"across zone and hole", Point{X: 4.95, Y: 52.05, Z: 80}, Point{X: 5.15, Y: 52.05, Z: 80}, false},
+		{"inside zone", Point{X: 5.01, Y: 52.01, Z: 80}, Point{X: 5.02, Y: 52.02, Z: 80}, false},
+		{"under floor", Point{X: 4.95, Y: 52.05, Z: 30}, Point{X: 5.15, Y: 52.05, Z: 30}, true},
+		{"at ceiling", Point{X: 4.95, Y: 52.05, Z: 120}, Point{X: 5.15, Y: 52.05, Z: 120}, true},
+		{"climbing into band", Point{X: 4.95, Y: 52.05, Z: 30}, Point{X: 5.15, Y: 52.05, Z: 80}, false},
+		{"outside buffer", Point{X: 5.305, Y: 52.0, Z: 0}, Point{X: 5.305, Y: 52.1, Z: 0}, true},
+		{"inside buffer", Point{X: 5.302, Y: 52.0, Z: 0}, Point{X: 5.302, Y: 52.1, Z: 0}, false},
+		{"climbing through band", Point{X: 5.41, Y: 52.01, Z: 0}, Point{X: 5.49, Y: 52.09, Z: 200}, false},
+		{"descending through band", Point{X: 5.49, Y: 52.09, Z: 200}, Point{X: 5.41, Y: 52.01, Z: 0}, false},
+		{"vertical through band", Point{X: 5.45, Y: 52.05, Z: 0}, Point{X: 5.45, Y: 52.05, Z: 200}, false},
+		{"climbing below band", Point{X: 5.41, Y: 52.01, Z: 0}, Point{X: 5.49, Y: 52.09, Z: 50}, true},
+		{"descending above band", Point{X: 5.41, Y: 52.01, Z: 200}, Point{X: 5.49, Y: 52.09, Z: 70}, true},
+		{"clear of all zones", Point{X: 5.6, Y: 52.0, Z: 0}, Point{X: 5.7, Y: 52.1, Z: 100}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPathClear(tt.p1, tt.p2, zones); got != tt.want {
+				t.Errorf("IsPathClear(%v, %v) = %v, want %v", tt.p1, tt.p2, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPathClearDynamicZones(t *testing.T) {
+	temporary := squareZone(5.0, 52.0, 0.1)
+	temporary.Floor = 60
+	temporary.Ceiling = 70
+	zones := NewZoneIndex(nil).WithDynamicZones(NewZoneIndex([]Polygon{temporary}))
+
+	climb := LineSegment{P1: Point{X: 5.05, Y: 52.05, Z: 0}, P2: Point{X: 5.05, Y: 52.05, Z: 120}}
+	if IsPathClear(climb.P1, climb.P2, zones) || zones.isClearOfDynamicZones(climb) {
+		t.Error("vertical segment through a temporary zone's band is clear")
+	}
+	below := LineSegment{P1: Point{X: 5.05, Y: 52.05, Z: 0}, P2: Point{X: 5.06, Y: 52.06, Z: 0}}
+	if !IsPathClear(below.P1, below.P2, zones) || !zones.isClearOfDynamicZones(below) {
+		t.Error("segment below a temporary zone's floor is blocked")
+	}
+}
+
+func TestDistanceMeters(t *testing.T) {
+	tests := []struct {
+		name   string
+		p1, p2 Point
+		want   float64
+	}{
+		{"one degree of latitude", Point{X: 5, Y: 52}, Point{X: 5, Y: 53}, 111194.9},
+		{"one degree of longitude at the equator", Point{X: 0, Y: 0}, Point{X: 1, Y: 0}, 111194.9},
+		{"one degree of longitude at 60 degrees", Point{X: 5, Y: 60}, Point{X: 6, Y: 60}, 55596.9},
+		{"climb only", Point{X: 5, Y: 52, Z: 0}, Point{X: 5, Y: 52, Z: 120}, 120},
+		{"same point", Point{X: 5, Y: 52, Z: 50}, Point{X: 5, Y: 52, Z: 50}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.p1.DistanceMeters(tt.p2); math.Abs(got-tt.want) > 0.5 {
+			t.Errorf("%s: DistanceMeters = %.1f, want %.1f", tt.name, got, tt.want)
+		}
+	}
+
+	// The altitude difference adds to the ground distance like a right triangle
+	p1, p2 := Point{X: 5, Y: 52, Z: 0}, Point{X: 5, Y: 52.001, Z: 100}
+	want := math.Hypot(p1.HorizontalDistanceMeters(p2), 100)
+	if got := p1.DistanceMeters(p2); math.Abs(got-want) > 1e-6 {
+		t.Errorf("DistanceMeters with climb = %v, want %v", got, want)
+	}
+}
+
+func TestCostModelHeuristicIsDistance(t *testing.T) {
+	costs := &CostModel{}
+	from, to := Point{X: 5, Y: 52, Z: 0}, Point{X: 5.1, Y: 52.05, Z: 60}
+	if got, want := costs.EdgeCost(from, to), from.DistanceMeters(to); got != want {
+		t.Errorf("EdgeCost = %v, want the distance %v", got, want)
+	}
+	if got, want := costs.Heuristic(from, to), costs.EdgeCost(from, to); got > want {
+		t.Errorf("Heuristic = %v exceeds the edge cost %v", got, want)
+	}
+}
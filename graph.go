@@ -1,13 +1,14 @@
 package main
 
-// Graph represents a graph for pathfinding (used by both visibility graph and PRM)
-type Graph struct {
-	Nodes map[int]Point
-	Edges map[int][]Edge
+// SearchGraph is the read-only view of a graph that A* searches on
+type SearchGraph interface {
+	NodePoint(id int) Point
+	Neighbors(id int) []Edge
+	Heuristic(from, to Point) float64 // Lower bound of the cost between two points
 }
 
 // Edge represents a connection between two nodes with a cost
 type Edge struct {
 	To   int     // Index of the destination node
-	Cost float64 // Distance cost
+	Cost float64 // Distance cost in meters
 }
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// KDTree is a static 2D k-d tree over node points used for neighbor queries
+// The tree is stored implicitly: the median of ids[lo:hi] is the subtree root
+type KDTree struct {
+	points []Point
+	ids    []int
+}
+
+// NewKDTree builds a k-d tree over the given points
+// IDs returned by queries are indices into the points slice
+func NewKDTree(points []Point) *KDTree {
+	t := &KDTree{
+		points: points,
+		ids:    make([]int, len(points)),
+	}
+	for i := range t.ids {
+		t.ids[i] = i
+	}
+	t.build(0, len(t.ids), 0)
+	return t
+}
+
+func (t *KDTree) build(lo, hi, depth int) {
+	if hi-lo <= 1 {
+		return
+	}
+	ids := t.ids[lo:hi]
+	sort.Slice(ids, func(i, j int) bool {
+		return kdAxisValue(t.points[ids[i]], depth) < kdAxisValue(t.points[ids[j]], depth)
+	})
+	mid := (lo + hi) / 2
+	t.build(lo, mid, depth+1)
+	t.build(mid+1, hi, depth+1)
+}
+
+// kdAxisValue returns the coordinate used to split at the given depth
+func kdAxisValue(p Point, depth int) float64 {
+	if depth%2 == 0 {
+		return p.X
+	}
+	return p.Y
+}
+
+// RadiusSearch returns the IDs of all points within radius (in degrees) of p
+func (t *KDTree) RadiusSearch(p Point, radius float64) []int {
+	var result []int
+	t.radiusSearch(p, radius, 0, len(t.ids), 0, &result)
+	return result
+}
+
+func (t *KDTree) radiusSearch(p Point, radius float64, lo, hi, depth int, result *[]int) {
+	if lo >= hi {
+		return
+	}
+	mid := (lo + hi) / 2
+	id := t.ids[mid]
+	if distance(p, t.points[id]) <= radius {
+		*result = append(*result, id)
+	}
+
+	diff := kdAxisValue(p, depth) - kdAxisValue(t.points[id], depth)
+	if diff <= radius {
+		t.radiusSearch(p, radius, lo, mid, depth+1, result)
+	}
+	if diff >= -radius {
+		t.radiusSearch(p, radius, mid+1, hi, depth+1, result)
+	}
+}
+
+// Nearest returns the ID of the point closest to p and its distance in degrees
+func (t *KDTree) Nearest(p Point) (int, float64) {
+	nearest := t.KNearest(p, 1)
+	if len(nearest) == 0 {
+		return -1, math.MaxFloat64
+	}
+	return nearest[0], distance(p, t.points[nearest[0]])
+}
+
+// KNearest returns the IDs of the k points closest to p, nearest first
+func (t *KDTree) KNearest(p Point, k int) []int {
+	if k <= 0 {
+		return nil
+	}
+	candidates := &kdMaxHeap{}
+	t.kNearest(p, k, 0, len(t.ids), 0, candidates)
+
+	result := make([]int, candidates.Len())
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = heap.Pop(candidates).(kdCandidate).id
+	}
+	return result
+}
+
+func (t *KDTree) kNearest(p Point, k, lo, hi, depth int, candidates *kdMaxHeap) {
+	if lo >= hi {
+		return
+	}
+	mid := (lo + hi) / 2
+	id := t.ids[mid]
+	dist := distance(p, t.points[id])
+	if candidates.Len() < k {
+		heap.Push(candidates, kdCandidate{id: id, dist: dist})
+	} else if dist < (*candidates)[0].dist {
+		(*candidates)[0] = kdCandidate{id: id, dist: dist}
+		heap.Fix(candidates, 0)
+	}
+
+	// Search the side containing p first, then the other side if it can still hold closer points
+	diff := kdAxisValue(p, depth) - kdAxisValue(t.points[id], depth)
+	nearLo, nearHi, farLo, farHi := lo, mid, mid+1, hi
+	if diff > 0 {
+		nearLo, nearHi, farLo, farHi = mid+1, hi, lo, mid
+	}
+	t.kNearest(p, k, nearLo, nearHi, depth+1, candidates)
+	if candidates.Len() < k || math.Abs(diff) < (*candidates)[0].dist {
+		t.kNearest(p, k, farLo, farHi, depth+1, candidates)
+	}
+}
+
+// kdCandidate is a point considered during a k-nearest search
+type kdCandidate struct {
+	id   int
+	dist float64
+}
+
+// kdMaxHeap keeps the current k best candidates with the farthest on top
+type kdMaxHeap []kdCandidate
+
+func (h kdMaxHeap) Len() int            { return len(h) }
+func (h kdMaxHeap) Less(i, j int) bool  { return h[i].dist > h[j].dist }
+func (h kdMaxHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *kdMaxHeap) Push(x interface{}) { *h = append(*h, x.(kdCandidate)) }
+
+func (h *kdMaxHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	c := old[n-1]
+	*h = old[0 : n-1]
+	return c
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func randomPoints(rng *rand.Rand, count int) []Point {
+	points := make([]Point, count)
+	for i := range points {
+		points[i] = Point{X: rng.Float64(), Y: rng.Float64()}
+	}
+	return points
+}
+
+func TestKDTreeRadiusSearch(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	points := randomPoints(rng, 2000)
+	tree := NewKDTree(points)
+
+	for _, radius := range []float64{0, 0.01, 0.05, 0.2, 2} {
+		for i := 0; i < 100; i++ {
+			p := Point{X: rng.Float64(), Y: rng.Float64()}
+			var want []int
+			for j, q := range points {
+				if distance(p, q) <= radius {
+					want = append(want, j)
+				}
+			}
+			if got := sortedInts(tree.RadiusSearch(p, radius)); !equalInts(got, want) {
+				t.Fatalf("RadiusSearch(%v, %v) = %v, want %v", p, radius, got, want)
+			}
+		}
+	}
+}
+
+func TestKDTreeKNearest(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	points := randomPoints(rng, 2000)
+	tree := NewKDTree(points)
+
+	for _, k := range []int{1, 5, 30, 2000, 3000} {
+		for i := 0; i < 100; i++ {
+			p := Point{X: rng.Float64(), Y: rng.Float64()}
+			ids := make([]int, len(points))
+			for j := range ids {
+				ids[j] = j
+			}
+			sort.Slice(ids, func(a, b int) bool { return distance(p, points[ids[a]]) < distance(p, points[ids[b]]) })
+			want := ids[:min(k, len(ids))]
+
+			if got := tree.KNearest(p, k); !equalInts(got, want) {
+				t.Fatalf("KNearest(%v, %d) = %v, want %v", p, k, got, want)
+			}
+		}
+	}
+}
+
+func TestKDTreeEmpty(t *testing.T) {
+	tree := NewKDTree(nil)
+	if got := tree.RadiusSearch(Point{}, 1); len(got) != 0 {
+		t.Errorf("RadiusSearch on empty tree = %v", got)
+	}
+	if got := tree.KNearest(Point{}, 3); len(got) != 0 {
+		t.Errorf("KNearest on empty tree = %v", got)
+	}
+	if id, _ := tree.Nearest(Point{}); id != -1 {
+		t.Errorf("Nearest on empty tree = %d, want -1", id)
+	}
+}
@@ -1,15 +1,22 @@
 package main
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 )
 
 type Point struct {
 	X float64 `json:"x"`
 	Y float64 `json:"y"`
+	Z float64 `json:"z,omitempty"` // Height above ground level in meters
 }
 
 type BoundingBox struct {
@@ -20,21 +27,106 @@ type BoundingBox struct {
 }
 
 type RouteRequest struct {
-	Start Point `json:"start"`
-	End   Point `json:"end"`
+	Start Point   `json:"start"`
+	End   Point   `json:"end"`
+	Via   []Point `json:"via,omitempty"` // Stops visited in order between start and end
+
+	Alternatives int `json:"alternatives,omitempty"` // Materially different routes to return besides the best one
+
+	CorridorWidthMeters     float64 `json:"corridorWidthMeters,omitempty"`     // Width of the flight geography around the route; 0 skips the corridor
+	ContingencyBufferMeters float64 `json:"contingencyBufferMeters,omitempty"` // Extra buffer around the flight geography
+	RouteOptions
+}
+
+// RouteOptions are the planning settings shared by route and tour requests
+type RouteOptions struct {
+	SmoothIterations  int      `json:"smoothIterations,omitempty"`  // Random shortcut attempts after greedy smoothing, at most maxSmoothIterations
+	RestrictedPenalty *float64 `json:"restrictedPenalty,omitempty"` // Overrides the default restricted zone penalty
+	Permit            *Permit  `json:"permit,omitempty"`            // Zones the operator is authorized to enter
+	MinAltitude       float64  `json:"minAltitude,omitempty"`       // Lowest graph layer the route may use, in meters
+	MaxAltitude       float64  `json:"maxAltitude,omitempty"`       // Highest graph layer the route may use, 0 for no limit
+
+	TurnRadiusMeters float64  `json:"turnRadiusMeters,omitempty"` // Minimum turning radius, enables Dubins trajectories
+	StartHeading     *float64 `json:"startHeading,omitempty"`     // Degrees clockwise from north
+	EndHeading       *float64 `json:"endHeading,omitempty"`       // Degrees clockwise from north
+
+	DepartureTime  *time.Time `json:"departureTime,omitempty"`  // Defaults to now, selects active temporary zones
+	CruiseSpeedMps float64    `json:"cruiseSpeedMps,omitempty"` // Overrides the vehicle's cruise speed
+
+	Vehicle *VehicleProfile `json:"vehicle,omitempty"` // Overrides the default vehicle profile, missing values use the default
 }
 
 type RouteResponse struct {
-	Path           []Point `json:"path"`
-	Success        bool    `json:"success"`
-	Message        string  `json:"message,omitempty"`
-	DistanceMeters float64 `json:"distanceMeters,omitempty"`
+	Path                     []Point       `json:"path"`
+	Success                  bool          `json:"success"`
+	Message                  string        `json:"message,omitempty"`
+	DistanceMeters           float64       `json:"distanceMeters,omitempty"`
+	AirDistanceMeters        float64       `json:"airDistanceMeters,omitempty"`        // Distance in still-air meters with the wind, airtime times airspeed
+	RestrictedDistanceMeters float64       `json:"restrictedDistanceMeters,omitempty"` // Distance flown inside restricted zones
+	ZonesAvoided             []AvoidedZone `json:"zonesAvoided,omitempty"`             // No-fly zones on the straight lines between stops
+	RawWaypoints             int           `json:"rawWaypoints,omitempty"`             // Waypoints returned by A*
+	SmoothedWaypoints        int           `json:"smoothedWaypoints,omitempty"`        // Waypoints after shortcutting
+
+	AltitudeProfile []ProfilePoint `json:"altitudeProfile,omitempty"` // Per-waypoint flight altitude over the terrain
+
+	Timing        *FlightTiming  `json:"timing,omitempty"`        // Total flight time, per-waypoint times and ETA
+	Energy        *EnergySummary `json:"energy,omitempty"`        // Battery use per leg between landings
+	ChargingStops []ChargingStop `json:"chargingStops,omitempty"` // Charging sites the route lands at
+
+	Legs        []RouteLeg `json:"legs,omitempty"`        // Sections between consecutive stops, with via points
+	StopIndexes []int      `json:"stopIndexes,omitempty"` // Index in the path of the start, every via point and the end
+
+	Trajectory               []Point `json:"trajectory,omitempty"`               // Densified Dubins trajectory for fixed-wing drones
+	TrajectoryDistanceMeters float64 `json:"trajectoryDistanceMeters,omitempty"` // Length of the trajectory
+
+	FlightWindow         *TimeWindow `json:"flightWindow,omitempty"`         // Estimated departure and arrival
+	ActiveTemporaryZones int         `json:"activeTemporaryZones,omitempty"` // Temporary zones avoided for the flight window
+
+	Alternatives []AlternativeRoute `json:"alternatives,omitempty"` // Other routes, in the order they were found
+
+	Corridor         *GeoJSONFeatureCollection `json:"corridor,omitempty"`         // Flight geography and contingency volume polygons
+	CorridorConflict bool                      `json:"corridorConflict,omitempty"` // The contingency volume overlaps a no-fly zone
+}
+
+// AlternativeRoute is a route that differs materially from the best route
+type AlternativeRoute struct {
+	Path                     []Point        `json:"path"`
+	DistanceMeters           float64        `json:"distanceMeters"`
+	RestrictedDistanceMeters float64        `json:"restrictedDistanceMeters,omitempty"`
+	Trajectory               []Point        `json:"trajectory,omitempty"`
+	AltitudeProfile          []ProfilePoint `json:"altitudeProfile,omitempty"` // Per-waypoint flight altitude over the terrain
+	Timing                   *FlightTiming  `json:"timing,omitempty"`
+	ChargingStops            []ChargingStop `json:"chargingStops,omitempty"`
+	Overlap                  float64        `json:"overlap"` // Largest share inside the corridor of an earlier route
+	Summary                  string         `json:"summary"` // How the route compares to the best route
 }
 
 var (
 	globalPRMGraph   *PRMGraph
 	globalNoFlyZones []Polygon
+	globalZoneIndex  *ZoneIndex
 	prmMutex         sync.RWMutex
+
+	globalBufferConfig   BufferConfig
+	globalTemporaryZones = NewTemporaryZoneStore(nil)
+
+	// Extra cost per meter flown inside "Beperkt toegestaan" zones
+	globalRestrictedPenalty = 2.0
+
+	// Default vehicle profile used to estimate flight times
+	globalVehicle = DefaultVehicleProfile
+
+	// Sites where routes beyond the vehicle's range can recharge
+	globalChargingSites []ChargingSite
+
+	// Wind field used for airtime costs, nil for still air
+	globalWind *WindField
+
+	// Flight altitudes in meters the PRM graph is built at, empty for a single ground layer
+	globalAltitudeLayers []float64
+
+	// Terrain clearance and AGL ceiling, nil when no elevation raster is loaded
+	globalTerrain *TerrainClearance
 )
 
 // buildPRMGraphIfNeeded builds the PRM graph if it doesn't exist
@@ -59,7 +151,7 @@ func buildPRMGraphIfNeeded() error {
 	log.Printf("   No-fly zones: %d polygons\n", len(globalNoFlyZones))
 
 	// Build the graph
-	graph := BuildPRMGraph(numSamples, connectionRadius, globalNoFlyZones)
+	graph := BuildPRMGraph(numSamples, connectionRadius, globalZoneIndex.WithFilter(isHardZone).WithTerrain(globalTerrain), globalAltitudeLayers)
 
 	// Save to global variable
 	prmMutex.Lock()
@@ -81,7 +173,7 @@ func buildPRMGraphIfNeeded() error {
 func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 
 		// Handle preflight
@@ -94,6 +186,68 @@ func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// adminToken is the bearer token required to change temporary zones and to
+// call the /admin endpoints; they are disabled when it is empty
+var adminToken string
+
+// adminMiddleware only passes requests that carry the admin token
+// Endpoints behind it send no CORS headers, and the Authorization header makes
+// browsers preflight cross-origin requests, so other sites cannot call them
+func adminMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if adminToken == "" {
+			log.Printf("❌ %s %s called without an admin token configured\n", r.Method, r.URL.Path)
+			http.Error(w, "Changes require the server to be started with an admin token", http.StatusForbidden)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			log.Printf("❌ Unauthorized request to %s\n", r.URL.Path)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// PlanOptions returns the planning options for a request against the no-fly
+// zones the current PRM graph was built for
+func (req RouteOptions) PlanOptions(zones *ZoneIndex) PlanOptions {
+	opts := PlanOptions{
+		Zones:             zones,
+		RestrictedPenalty: globalRestrictedPenalty,
+		SmoothIterations:  min(max(req.SmoothIterations, 0), maxSmoothIterations),
+		Permit:            req.Permit,
+		Altitude:          AltitudeBand{Min: req.MinAltitude, Max: req.MaxAltitude},
+		Terrain:           globalTerrain,
+		TemporaryZones:    globalTemporaryZones.Index(),
+		Departure:         time.Now(),
+		Vehicle:           globalVehicle,
+		ChargingSites:     globalChargingSites,
+		Wind:              globalWind,
+	}
+	if req.RestrictedPenalty != nil {
+		opts.RestrictedPenalty = math.Max(0, *req.RestrictedPenalty)
+	}
+	if req.DepartureTime != nil {
+		opts.Departure = *req.DepartureTime
+	}
+	if req.Vehicle != nil {
+		opts.Vehicle = req.Vehicle.WithDefaults(globalVehicle)
+	}
+	if req.CruiseSpeedMps > 0 {
+		opts.Vehicle.CruiseSpeedMps = req.CruiseSpeedMps
+	}
+	if req.TurnRadiusMeters > 0 {
+		opts.Kinematics = &Kinematics{
+			TurnRadius:   req.TurnRadiusMeters,
+			StartHeading: req.StartHeading,
+			EndHeading:   req.EndHeading,
+		}
+	}
+	return opts
+}
+
 func routeHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("========================================")
 	log.Println("📍 Route request received")
@@ -104,100 +258,115 @@ func routeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if _, ok := routeExportFormats[format]; !ok && format != "" && format != "json" {
+		log.Printf("❌ Unknown export format: %s\n", format)
+		http.Error(w, "Query parameter format must be json, geojson, kml, gpx, plan or waypoints", http.StatusBadRequest)
+		return
+	}
+
 	var req RouteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		log.Printf("❌ Invalid request body: %v\n", err)
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
-
-	log.Printf("   Start: (%.6f, %.6f)\n", req.Start.X, req.Start.Y)
-	log.Printf("   End:   (%.6f, %.6f)\n", req.End.X, req.End.Y)
-
-	// First, check if a straight line path is possible (no obstacles)
-	log.Println("🔍 Checking if straight line path is possible...")
-	straightLineClear := IsPathClear(req.Start, req.End, globalNoFlyZones)
-
-	if straightLineClear {
-		log.Println("✅ Straight line path is clear!")
-		distance := req.Start.DistanceMeters(req.End)
-
-		response := RouteResponse{
-			Path:           []Point{req.Start, req.End},
-			Success:        true,
-			Message:        "Direct straight line path (no obstacles)",
-			DistanceMeters: distance,
-		}
-
-		log.Printf("   Distance: %.2f meters (%.2f km)\n", distance, distance/1000)
-		log.Println("   Path: straight line (2 waypoints)")
-
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
-		log.Println("========================================")
+	if req.CorridorWidthMeters < 0 || req.ContingencyBufferMeters < 0 {
+		log.Println("❌ Negative corridor width or contingency buffer")
+		http.Error(w, "corridorWidthMeters and contingencyBufferMeters must not be negative", http.StatusBadRequest)
+		return
+	}
+	if len(req.Via) > maxTourStops {
+		log.Printf("❌ Too many via points: %d\n", len(req.Via))
+		http.Error(w, fmt.Sprintf("At most %d via points are allowed", maxTourStops), http.StatusBadRequest)
 		return
 	}
 
-	log.Println("⚠️  Straight line blocked - using PRM graph pathfinding...")
+	log.Printf("   Start: (%.6f, %.6f, %.0f m)\n", req.Start.X, req.Start.Y, req.Start.Z)
+	for i, via := range req.Via {
+		log.Printf("   Via %d: (%.6f, %.6f, %.0f m)\n", i+1, via.X, via.Y, via.Z)
+	}
+	log.Printf("   End:   (%.6f, %.6f, %.0f m)\n", req.End.X, req.End.Y, req.End.Z)
+	if req.Permit != nil {
+		log.Printf("   Permit: %q (%d categories, %d zones)\n", req.Permit.Name, len(req.Permit.Categories), len(req.Permit.GIDs))
+	}
 
-	// Check if PRM graph is available
-	prmMutex.RLock()
-	prmGraph := globalPRMGraph
-	prmMutex.RUnlock()
+	prmGraph, zones := currentPlanningState()
+	opts := req.PlanOptions(zones)
+	stops := append(append([]Point{req.Start}, req.Via...), req.End)
+	plan := PlanRoute(prmGraph, stops, opts)
 
-	if prmGraph == nil {
-		log.Println("❌ PRM graph not available")
+	if !plan.Success && prmGraph == nil {
 		http.Error(w, "PRM graph not built. Call /buildPRMGraph first", http.StatusBadRequest)
 		log.Println("========================================")
 		return
 	}
 
-	// Create a temporary graph with start and end points connected
-	log.Println("🔗 Connecting start and end points to graph...")
-	tempGraph, startNodeID, endNodeID := prmGraph.CreateGraphWithStartEnd(req.Start, req.End, globalNoFlyZones)
-
-	if startNodeID == -1 || endNodeID == -1 {
-		log.Println("❌ Could not connect start or end point to graph")
-		response := RouteResponse{
-			Success: false,
-			Message: "Could not connect start or end point to the graph (possibly blocked by no-fly zones)",
-		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
-		log.Println("========================================")
-		return
+	response := RouteResponse{
+		Path:                     plan.Path,
+		Success:                  plan.Success,
+		Message:                  plan.Message,
+		DistanceMeters:           plan.DistanceMeters,
+		RestrictedDistanceMeters: plan.RestrictedDistanceMeters,
+		ZonesAvoided:             plan.ZonesAvoided,
+		RawWaypoints:             plan.RawWaypoints,
+		SmoothedWaypoints:        len(plan.Path),
+		AltitudeProfile:          plan.AltitudeProfile,
+		Trajectory:               plan.Trajectory,
+		TrajectoryDistanceMeters: plan.TrajectoryMeters,
+		Timing:                   plan.Timing,
+		Energy:                   plan.Energy,
+		ChargingStops:            plan.ChargingStops,
+		FlightWindow:             plan.FlightWindow,
+		ActiveTemporaryZones:     plan.ActiveTemporaryZones,
 	}
 
-	log.Printf("   ✅ Start connected as node %d\n", startNodeID)
-	log.Printf("   ✅ End connected as node %d\n", endNodeID)
-
-	// Convert to standard graph format
-	graph := tempGraph.ConvertToGraph()
-
-	// Run A* on the graph with start and end
-	log.Println("🔍 Running A* on PRM graph...")
-	path, success := AStarPathOnGraph(graph, startNodeID, endNodeID)
+	if opts.Wind != nil {
+		response.AirDistanceMeters = plan.AirMeters
+	}
+	if len(req.Via) > 0 {
+		response.Legs = plan.Legs
+		response.StopIndexes = plan.StopIndexes
+	}
 
-	// Calculate distance
-	var distanceMeters float64
-	if success && len(path) > 1 {
-		for i := 0; i < len(path)-1; i++ {
-			distanceMeters += path[i].DistanceMeters(path[i+1])
+	if plan.Success && req.CorridorWidthMeters > 0 {
+		flown := plan.Path
+		if plan.Trajectory != nil {
+			flown = plan.Trajectory
 		}
+		volume := NewOperationalVolume(flown, req.CorridorWidthMeters, req.ContingencyBufferMeters, opts, plan.FlightWindow)
+		if len(volume.Conflicts) == 0 {
+			log.Printf("✅ Corridor of %.0f m with %.0f m contingency buffer is clear of no-fly zones\n", volume.WidthMeters, volume.ContingencyMeters)
+		} else {
+			log.Printf("⚠️  Corridor of %.0f m with %.0f m contingency buffer overlaps %d no-fly zones\n", volume.WidthMeters, volume.ContingencyMeters, len(volume.Conflicts))
+			response.CorridorConflict = true
+		}
+		corridor := volume.GeoJSON()
+		response.Corridor = &corridor
 	}
 
-	response := RouteResponse{
-		Path:           path,
-		Success:        success,
-		DistanceMeters: distanceMeters,
+	if plan.Success && req.Alternatives > 0 {
+		count := min(req.Alternatives, maxAlternatives)
+		for _, alternative := range PlanAlternatives(prmGraph, stops, plan, count, opts) {
+			response.Alternatives = append(response.Alternatives, AlternativeRoute{
+				Path:                     alternative.Path,
+				DistanceMeters:           alternative.DistanceMeters,
+				RestrictedDistanceMeters: alternative.RestrictedDistanceMeters,
+				Trajectory:               alternative.Trajectory,
+				AltitudeProfile:          alternative.AltitudeProfile,
+				Timing:                   alternative.Timing,
+				ChargingStops:            alternative.ChargingStops,
+				Overlap:                  alternative.Overlap,
+				Summary:                  alternative.Summary,
+			})
+		}
+		log.Printf("🔀 %d of %d alternative routes found\n", len(response.Alternatives), count)
 	}
 
-	if !success {
-		log.Println("❌ No path found on PRM graph")
-		response.Message = "No path found on PRM graph"
-	} else {
+	if plan.Success {
+		path := plan.Path
 		log.Printf("✅ Path found with %d waypoints\n", len(path))
-		log.Printf("   Distance: %.2f meters (%.2f km)\n", distanceMeters, distanceMeters/1000)
+		log.Printf("   Distance: %.2f meters (%.2f km)\n", plan.DistanceMeters, plan.DistanceMeters/1000)
 		log.Println("   Path preview (first/last 3 waypoints):")
 		for i := 0; i < len(path) && i < 3; i++ {
 			log.Printf("      %d: (%.6f, %.6f)\n", i, path[i].X, path[i].Y)
@@ -211,11 +380,29 @@ func routeHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if _, ok := routeExportFormats[format]; ok {
+		if !plan.Success {
+			http.Error(w, plan.Message, http.StatusUnprocessableEntity)
+		} else {
+			writeRouteExport(w, format, response, opts)
+		}
+		log.Println("========================================")
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 	log.Println("========================================")
 }
 
+// currentPlanningState returns the PRM graph and the no-fly zones it was
+// built for, read together so a zone reload cannot pair them up wrongly
+func currentPlanningState() (*PRMGraph, *ZoneIndex) {
+	prmMutex.RLock()
+	defer prmMutex.RUnlock()
+	return globalPRMGraph, globalZoneIndex
+}
+
 // GET /health - Health check endpoint
 func healthHandler(w http.ResponseWriter, r *http.Request) {
 	prmMutex.RLock()
@@ -275,14 +462,105 @@ func getPRMGraphLinesHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// isFlagSet reports whether a command-line flag was passed explicitly
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
 func main() {
+	bufferMeters := flag.Float64("buffer", 0, "Default safety buffer around no-fly zones in meters")
+	flag.Float64Var(&globalRestrictedPenalty, "restricted-penalty", globalRestrictedPenalty,
+		"Extra cost per meter flown inside restricted zones")
+	cruiseSpeed := flag.Float64("cruise-speed", DefaultVehicleProfile.CruiseSpeedMps,
+		"Default cruise speed in meters per second, overrides the vehicle profile")
+	windFile := flag.String("wind", "wind.json", "JSON wind field with u/v components per grid cell")
+	chargingSitesFile := flag.String("charging-sites", "charging_sites.geojson", "GeoJSON file with charging/landing sites")
+	vehicleFile := flag.String("vehicle", "vehicle_profile.json", "JSON file with the default vehicle profile")
+	bufferConfigFile := flag.String("buffer-config", "nfz_buffers.json", "JSON file with per-category safety buffers")
+	terrainFile := flag.String("terrain", "terrain.asc", "ESRI ASCII grid elevation raster (WGS84) for terrain clearance")
+	minClearance := flag.Float64("min-clearance", 30, "Minimum height above terrain and obstacles in meters")
+	maxAGL := flag.Float64("max-agl", 120, "Maximum height above ground level in meters")
+	altitudeLayers := flag.String("altitude-layers", "", "Comma-separated flight altitudes in meters to build the PRM graph at")
+	watchZones := flag.Duration("watch-zones", 0, "Interval to check nfz-polygons for changed zones, 0 to disable")
+	flag.StringVar(&adminToken, "admin-token", "", "Bearer token required to change temporary zones and by the /admin endpoints, which are disabled without one")
+	flag.Parse()
+
 	log.Println("========================================")
 	log.Println("🚀 Drone Motion Planner Server (PRM-based)")
 	log.Println("========================================")
 
+	// Load safety buffer configuration
+	buffers, err := LoadBufferConfig(*bufferConfigFile)
+	if err != nil {
+		log.Printf("ℹ️  No buffer config loaded from %s: %v\n", *bufferConfigFile, err)
+		buffers = BufferConfig{DefaultMeters: *bufferMeters}
+	}
+	if isFlagSet("buffer") {
+		buffers.DefaultMeters = *bufferMeters
+	}
+	log.Printf("   Default safety buffer: %.0f meters (%d category overrides)\n",
+		buffers.DefaultMeters, len(buffers.Categories))
+
+	globalAltitudeLayers, err = parseAltitudeLayers(*altitudeLayers)
+	if err != nil {
+		log.Fatalf("❌ Invalid -altitude-layers: %v\n", err)
+	}
+
+	// Load default vehicle profile
+	globalVehicle, err = LoadVehicleProfile(*vehicleFile)
+	if err != nil {
+		log.Printf("ℹ️  No vehicle profile loaded from %s: %v\n", *vehicleFile, err)
+	}
+	if isFlagSet("cruise-speed") && *cruiseSpeed > 0 {
+		globalVehicle.CruiseSpeedMps = *cruiseSpeed
+	}
+	log.Printf("   Vehicle %q: cruise %.1f m/s, climb %.1f m/s, descent %.1f m/s\n",
+		globalVehicle.Name, globalVehicle.CruiseSpeedMps, globalVehicle.ClimbRateMps, globalVehicle.DescentRateMps)
+
+	if globalVehicle.HasBattery() {
+		log.Printf("   Battery %.0f Wh, %.1f Wh/km, %.0f%% reserve: range %.1f km\n",
+			globalVehicle.BatteryWh, globalVehicle.ConsumptionWhPerKm, globalVehicle.ReservePercent,
+			globalVehicle.RangeMeters()/1000)
+	}
+
+	// Load charging sites
+	globalChargingSites, err = LoadChargingSites(*chargingSitesFile)
+	if err != nil {
+		log.Printf("ℹ️  No charging sites loaded from %s: %v\n", *chargingSitesFile, err)
+	} else {
+		log.Printf("✅ Loaded %d charging sites\n", len(globalChargingSites))
+	}
+
+	// Load wind field
+	globalWind, err = LoadWindField(*windFile)
+	if err != nil {
+		log.Printf("ℹ️  No wind field loaded from %s: %v\n", *windFile, err)
+	} else {
+		log.Printf("✅ Loaded %dx%d wind field (max %.1f m/s)\n", globalWind.NCols, globalWind.NRows, globalWind.MaxSpeed())
+	}
+
+	// Load terrain elevation raster
+	if *minClearance >= *maxAGL {
+		log.Fatalf("❌ -min-clearance (%.0f m) must be below -max-agl (%.0f m)\n", *minClearance, *maxAGL)
+	}
+	terrain, err := LoadTerrain(*terrainFile)
+	if err != nil {
+		log.Printf("ℹ️  No terrain loaded from %s: %v\n", *terrainFile, err)
+	} else {
+		globalTerrain = &TerrainClearance{Terrain: terrain, MinClearance: *minClearance, MaxAGL: *maxAGL}
+		log.Printf("✅ Loaded %dx%d terrain grid (clearance %.0f m, AGL ceiling %.0f m)\n",
+			terrain.NCols, terrain.NRows, *minClearance, *maxAGL)
+	}
+
 	// Load no-fly zones from files
 	log.Println("Loading no-fly zones from files...")
-	noFlyZones, err := loadNoFlyZonesFromFiles()
+	noFlyZones, err := loadNoFlyZonesFromFiles(buffers)
 	if err != nil {
 		log.Printf("⚠️  Failed to load no-fly zones: %v\n", err)
 		log.Println("   Continuing without no-fly zones...")
@@ -291,11 +569,38 @@ func main() {
 		globalNoFlyZones = noFlyZones
 		log.Printf("✅ Loaded %d no-fly zone polygons\n", len(globalNoFlyZones))
 	}
+	globalZoneIndex = NewZoneIndex(globalNoFlyZones)
+	globalBufferConfig = buffers
+	log.Println("")
+
+	// Load temporary zones (NOTAM-style, with valid_from/valid_to properties)
+	log.Println("Loading temporary no-fly zones from files...")
+	temporaryZones, err := loadZonesFromDir("nfz-temporary", buffers)
+	if err != nil {
+		log.Printf("⚠️  Failed to load temporary zones: %v\n", err)
+	}
+	temporaryZones = activeOrFutureZones(temporaryZones, time.Now())
+	globalTemporaryZones = NewTemporaryZoneStore(temporaryZones)
+	log.Printf("✅ Loaded %d temporary zone polygons\n", len(temporaryZones))
 	log.Println("")
 
 	// Try to load existing PRM graph from file
 	log.Println("Checking for existing PRM graph file...")
-	if graph, err := LoadPRMGraph("prm_graph.json"); err == nil {
+	graph, err := LoadPRMGraph("prm_graph.json")
+	if err == nil && graph.Version != prmGraphVersion {
+		log.Printf("⚠️  Saved PRM graph has format version %d, expected %d\n", graph.Version, prmGraphVersion)
+		err = fmt.Errorf("graph version mismatch")
+	} else if err == nil && graph.ZoneSignature != globalZoneIndex.Signature() {
+		log.Println("⚠️  Saved PRM graph was built for different no-fly zones or buffers")
+		err = fmt.Errorf("zone signature mismatch")
+	} else if err == nil && graph.TerrainSignature != globalTerrain.Signature() {
+		log.Println("⚠️  Saved PRM graph was built for a different terrain or AGL limits")
+		err = fmt.Errorf("terrain signature mismatch")
+	} else if err == nil && !sameAltitudeLayers(graph.AltitudeLayers, globalAltitudeLayers) {
+		log.Printf("⚠️  Saved PRM graph was built for altitude layers %v\n", graph.AltitudeLayers)
+		err = fmt.Errorf("altitude layer mismatch")
+	}
+	if err == nil {
 		prmMutex.Lock()
 		globalPRMGraph = graph
 		prmMutex.Unlock()
@@ -305,7 +610,7 @@ func main() {
 			graph.BoundingBox.MinLon, graph.BoundingBox.MinLat,
 			graph.BoundingBox.MaxLon, graph.BoundingBox.MaxLat)
 	} else {
-		log.Println("ℹ️  No existing graph found, building new graph...")
+		log.Println("ℹ️  No usable graph found, building new graph...")
 		if err := buildPRMGraphIfNeeded(); err != nil {
 			log.Printf("❌ Failed to build PRM graph: %v\n", err)
 			log.Println("   Server will start but routing will not be available")
@@ -314,20 +619,41 @@ func main() {
 	log.Println("")
 
 	http.HandleFunc("/route", corsMiddleware(routeHandler))
+	http.HandleFunc("/tour", corsMiddleware(tourHandler))
+	http.HandleFunc("/validateRoute", corsMiddleware(validateRouteHandler))
 	http.HandleFunc("/getPRMGraphLines", corsMiddleware(getPRMGraphLinesHandler))
 	http.HandleFunc("/health", corsMiddleware(healthHandler))
+	http.HandleFunc("/temporaryZones", temporaryZonesMiddleware(temporaryZonesHandler))
+	http.HandleFunc("/airspace/point", corsMiddleware(airspacePointHandler))
+	http.HandleFunc("/airspace/bbox", corsMiddleware(airspaceBoxHandler))
+	http.HandleFunc("/admin/reloadZones", adminMiddleware(reloadZonesHandler))
 
 	log.Println("Server starting on :8080")
 	log.Println("")
 	log.Println("Endpoints:")
 	log.Println("  GET  /getPRMGraphLines   - Get PRM graph edges for visualization")
 	log.Println("  POST /route              - Compute route with start and end points")
+	log.Println("  POST /tour               - Optimize the order of stops visited from a depot")
+	log.Println("  POST /validateRoute      - Check an externally planned route against no-fly zones")
 	log.Println("  GET  /health             - Check server status")
+	log.Println("  GET  /temporaryZones     - List temporary no-fly zones")
+	log.Println("  POST /temporaryZones     - Add temporary no-fly zones (GeoJSON)")
+	log.Println("  DELETE /temporaryZones?gid=<gid> - Remove temporary no-fly zones")
+	log.Println("  GET  /airspace/point     - List the no-fly zones at a location")
+	log.Println("  GET  /airspace/bbox      - List the no-fly zones in a bounding box")
+	log.Println("  POST /admin/reloadZones  - Reload no-fly zones and repair the PRM graph")
 	log.Println("")
-	log.Println("CORS enabled for all origins")
+	log.Println("CORS enabled for all origins, except /admin and temporary zone changes")
+	if adminToken == "" {
+		log.Println("Temporary zone changes and admin endpoints disabled, set -admin-token to enable them")
+	}
 	log.Println("========================================")
 	log.Println("")
 
+	if *watchZones > 0 {
+		go watchNoFlyZones(noFlyZoneDir, *watchZones)
+	}
+
 	if err := http.ListenAndServe(":8080", nil); err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminMiddleware(t *testing.T) {
+	defer func(token string) { adminToken = token }(adminToken)
+	handler := adminMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		want          int
+	}{
+		{"disabled", "", "Bearer ", http.StatusForbidden},
+		{"missing token", "secret", "", http.StatusUnauthorized},
+		{"wrong token", "secret", "Bearer guess", http.StatusUnauthorized},
+		{"not a bearer token", "secret", "secret", http.StatusUnauthorized},
+		{"valid token", "secret", "Bearer secret", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adminToken = tt.token
+			r := httptest.NewRequest(http.MethodPost, "/admin/reloadZones", nil)
+			r.Header.Set("Origin", "https://example.com")
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status %d, want %d", w.Code, tt.want)
+			}
+			if origin := w.Header().Get("Access-Control-Allow-Origin"); origin != "" {
+				t.Errorf("Access-Control-Allow-Origin %q on an admin endpoint", origin)
+			}
+		})
+	}
+}
+
+func TestTemporaryZonesMiddleware(t *testing.T) {
+	defer func(token string) { adminToken = token }(adminToken)
+	adminToken = "secret"
+	handler := temporaryZonesMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		method        string
+		authorization string
+		want          int
+		wantCORS      bool
+	}{
+		{http.MethodGet, "", http.StatusNoContent, true},
+		{http.MethodOptions, "", http.StatusOK, true},
+		{http.MethodPost, "", http.StatusUnauthorized, false},
+		{http.MethodDelete, "", http.StatusUnauthorized, false},
+		{http.MethodDelete, "Bearer secret", http.StatusNoContent, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, "/temporaryZones?gid=1", nil)
+		if tt.authorization != "" {
+			r.Header.Set("Authorization", tt.authorization)
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s %q: status %d, want %d", tt.method, tt.authorization, w.Code, tt.want)
+		}
+		if cors := w.Header().Get("Access-Control-Allow-Origin") != ""; cors != tt.wantCORS {
+			t.Errorf("%s %q: CORS headers %v, want %v", tt.method, tt.authorization, cors, tt.wantCORS)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MAVLink commands and frames used in exported missions
+const (
+	mavCmdNavWaypoint   = 16
+	mavCmdNavLand       = 21
+	mavCmdNavTakeoff    = 22
+	mavCmdDoChangeSpeed = 178
+
+	mavFrameGlobal            = 0 // Altitude above mean sea level
+	mavFrameMission           = 2 // Command without a position
+	mavFrameGlobalRelativeAlt = 3 // Altitude above the home position
+)
+
+// defaultMissionAltitude is the flight altitude above home used for waypoints
+// without an altitude when no terrain raster is loaded
+const defaultMissionAltitude = 60.0
+
+// geofenceMarginMeters is the distance from the route within which no-fly
+// zones are added to the mission geofence
+const geofenceMarginMeters = 2000.0
+
+// missionItem is a single MAVLink mission command
+type missionItem struct {
+	Command int
+	Frame   int
+	Params  [7]float64 // param1-4, latitude, longitude, altitude; NaN for unused
+}
+
+// Mission is a route converted into MAVLink mission commands with a geofence
+type Mission struct {
+	Home      Point // Takeoff position, Z is the ground elevation above sea level
+	Items     []missionItem
+	Geofence  []Polygon // No-fly zones near the route, exported as exclusion polygons of their outer ring
+	Speed     float64   // Cruise speed in meters per second
+	FixedWing bool
+}
+
+// NewMission converts a planned route into a mission: a takeoff to the first
+// waypoint altitude, the cruise speed, a waypoint per path point, a landing and
+// takeoff at every charging stop and a landing at the end
+// With a terrain raster the waypoints are flown at their altitude above sea
+// level, otherwise at their altitude above home
+// The planner never saw the default altitude, so the mission is refused when
+// a takeoff, leg or landing at the exported altitudes enters a no-fly zone
+func NewMission(response RouteResponse, opts PlanOptions) (Mission, error) {
+	altitudes, aboveSeaLevel := routeAltitudes(response.Path, response.AltitudeProfile)
+	frame := mavFrameGlobalRelativeAlt
+	if aboveSeaLevel {
+		frame = mavFrameGlobal
+	} else {
+		for i := range altitudes {
+			if altitudes[i] <= 0 {
+				altitudes[i] = defaultMissionAltitude
+			}
+		}
+	}
+
+	legs := missionLegs(response, altitudes)
+	for _, zones := range opts.hardZoneIndexes(response.FlightWindow) {
+		for _, leg := range legs {
+			if !IsPathClear(leg.P1, leg.P2, zones) {
+				return Mission{}, fmt.Errorf("mission enters a no-fly zone at %.0f-%.0f m near (%.6f, %.6f)",
+					math.Min(leg.P1.Z, leg.P2.Z), math.Max(leg.P1.Z, leg.P2.Z), leg.P1.X, leg.P1.Y)
+			}
+		}
+	}
+
+	path := response.Path
+	mission := Mission{Home: path[0], Speed: opts.Vehicle.CruiseSpeedMps, FixedWing: opts.Kinematics != nil}
+	mission.Home.Z = 0
+	if response.AltitudeProfile != nil {
+		mission.Home.Z = response.AltitudeProfile[0].GroundMeters
+	}
+
+	nan := math.NaN()
+	position := func(command, i int) missionItem {
+		return missionItem{Command: command, Frame: frame, Params: [7]float64{0, 0, 0, nan, path[i].Y, path[i].X, altitudes[i]}}
+	}
+	landing := func(i int) missionItem {
+		item := position(mavCmdNavLand, i)
+		item.Params[6] = 0
+		if aboveSeaLevel {
+			item.Params[6] = response.AltitudeProfile[i].GroundMeters
+		}
+		return item
+	}
+
+	// Ground speed for multirotors, airspeed for fixed-wing drones
+	speedType := 1.0
+	if mission.FixedWing {
+		speedType = 0
+	}
+	mission.Items = append(mission.Items,
+		position(mavCmdNavTakeoff, 0),
+		missionItem{Command: mavCmdDoChangeSpeed, Frame: mavFrameMission, Params: [7]float64{speedType, mission.Speed, -1, 0, 0, 0, 0}},
+	)
+	for i := 1; i < len(path)-1; i++ {
+		if isChargingStop(response.ChargingStops, i) {
+			mission.Items = append(mission.Items, landing(i), position(mavCmdNavTakeoff, i))
+			continue
+		}
+		mission.Items = append(mission.Items, position(mavCmdNavWaypoint, i))
+	}
+	mission.Items = append(mission.Items, landing(len(path)-1))
+
+	mission.Geofence = missionGeofence(legs, opts, response.FlightWindow)
+	return mission, nil
+}
+
+// isChargingStop reports whether the waypoint is a charging stop
+func isChargingStop(stops []ChargingStop, waypoint int) bool {
+	for _, stop := range stops {
+		if stop.WaypointIndex == waypoint {
+			return true
+		}
+	}
+	return false
+}
+
+// missionLegs returns the segments the mission flies at their height above
+// the ground: the climb at takeoff, every leg between waypoints and the
+// descent at every landing
+// With an altitude profile each leg spans the lowest to the highest height
+// above the terrain flown on it
+func missionLegs(response RouteResponse, altitudes []float64) []LineSegment {
+	path := response.Path
+	height := func(i int) float64 {
+		if response.AltitudeProfile != nil {
+			return response.AltitudeProfile[i].AGLMeters
+		}
+		return altitudes[i]
+	}
+	vertical := func(i int) LineSegment {
+		ground := path[i]
+		ground.Z = 0
+		air := path[i]
+		air.Z = height(i)
+		return LineSegment{P1: ground, P2: air}
+	}
+
+	legs := []LineSegment{vertical(0)}
+	for i := 0; i < len(path)-1; i++ {
+		leg := LineSegment{P1: path[i], P2: path[i+1]}
+		if response.AltitudeProfile != nil {
+			leg.P1.Z = *response.AltitudeProfile[i].LegMinAGL
+			leg.P2.Z = *response.AltitudeProfile[i].LegMaxAGL
+		} else {
+			leg.P1.Z = altitudes[i]
+			leg.P2.Z = altitudes[i+1]
+		}
+		legs = append(legs, leg)
+		if i+1 == len(path)-1 || isChargingStop(response.ChargingStops, i+1) {
+			legs = append(legs, vertical(i+1))
+		}
+	}
+	return legs
+}
+
+// missionGeofence returns the no-fly zones the mission may not enter that lie
+// within geofenceMarginMeters of its legs, including temporary zones active
+// during the flight window
+// The fence holds each zone's outer ring without its safety buffer, so zones
+// whose altitude band the legs near them stay clear of, and zones whose
+// footprint the path enters through a hole or below the floor, are left out
+func missionGeofence(legs []LineSegment, opts PlanOptions, window *TimeWindow) []Polygon {
+	var fence []Polygon
+	for _, zones := range opts.hardZoneIndexes(window) {
+		seen := make(map[int]bool)
+		for _, leg := range legs {
+			for _, zone := range zones.ZonesNearSegment(leg, geofenceMarginMeters) {
+				if seen[zone] {
+					continue
+				}
+				seen[zone] = true
+				if !entersFootprint(legs, zones.Zones[zone]) {
+					fence = append(fence, zones.Zones[zone])
+				}
+			}
+		}
+	}
+	return fence
+}
+
+// entersFootprint reports whether any leg lies inside or crosses the outer
+// ring of a zone, at any altitude
+func entersFootprint(legs []LineSegment, zone Polygon) bool {
+	outer := Polygon{Vertices: zone.Vertices}
+	for _, leg := range legs {
+		if isPointInRing(leg.P1, zone.Vertices) || DoesSegmentIntersectPolygon(leg, outer) {
+			return true
+		}
+	}
+	return false
+}
+
+// QGroundControl .plan file structure
+type qgcPlan struct {
+	FileType      string         `json:"fileType"`
+	GroundStation string         `json:"groundStation"`
+	Version       int            `json:"version"`
+	Mission       qgcMission     `json:"mission"`
+	GeoFence      qgcGeoFence    `json:"geoFence"`
+	RallyPoints   qgcRallyPoints `json:"rallyPoints"`
+}
+
+type qgcMission struct {
+	Version             int              `json:"version"`
+	FirmwareType        int              `json:"firmwareType"`
+	VehicleType         int              `json:"vehicleType"`
+	CruiseSpeed         float64          `json:"cruiseSpeed"`
+	HoverSpeed          float64          `json:"hoverSpeed"`
+	PlannedHomePosition [3]float64       `json:"plannedHomePosition"`
+	Items               []qgcMissionItem `json:"items"`
+}
+
+type qgcMissionItem struct {
+	Type                string     `json:"type"`
+	AutoContinue        bool       `json:"autoContinue"`
+	Command             int        `json:"command"`
+	DoJumpID            int        `json:"doJumpId"`
+	Frame               int        `json:"frame"`
+	Params              []*float64 `json:"params"`
+	Altitude            *float64   `json:"Altitude,omitempty"`
+	AltitudeMode        int        `json:"AltitudeMode,omitempty"`
+	AMSLAltAboveTerrain *float64   `json:"AMSLAltAboveTerrain"`
+}
+
+type qgcGeoFence struct {
+	Version  int               `json:"version"`
+	Circles  []interface{}     `json:"circles"`
+	Polygons []qgcFencePolygon `json:"polygons"`
+}
+
+type qgcFencePolygon struct {
+	Inclusion bool         `json:"inclusion"`
+	Polygon   [][2]float64 `json:"polygon"` // Latitude, longitude pairs
+	Version   int          `json:"version"`
+}
+
+type qgcRallyPoints struct {
+	Version int           `json:"version"`
+	Points  []interface{} `json:"points"`
+}
+
+// MAVLink vehicle types used in .plan files
+const (
+	mavTypeFixedWing = 1
+	mavTypeQuadrotor = 2
+)
+
+// QGCPlan returns the mission as a QGroundControl .plan document
+func (m Mission) QGCPlan() qgcPlan {
+	vehicleType := mavTypeQuadrotor
+	if m.FixedWing {
+		vehicleType = mavTypeFixedWing
+	}
+	plan := qgcPlan{
+		FileType:      "Plan",
+		GroundStation: "QGroundControl",
+		Version:       1,
+		Mission: qgcMission{
+			Version:             2,
+			VehicleType:         vehicleType,
+			CruiseSpeed:         m.Speed,
+			HoverSpeed:          m.Speed,
+			PlannedHomePosition: [3]float64{m.Home.Y, m.Home.X, m.Home.Z},
+			Items:               []qgcMissionItem{},
+		},
+		GeoFence:    qgcGeoFence{Version: 2, Circles: []interface{}{}, Polygons: []qgcFencePolygon{}},
+		RallyPoints: qgcRallyPoints{Version: 2, Points: []interface{}{}},
+	}
+
+	for i, item := range m.Items {
+		qgcItem := qgcMissionItem{
+			Type:         "SimpleItem",
+			AutoContinue: true,
+			Command:      item.Command,
+			DoJumpID:     i + 1,
+			Frame:        item.Frame,
+			Params:       make([]*float64, len(item.Params)),
+		}
+		for j := range item.Params {
+			if !math.IsNaN(item.Params[j]) {
+				qgcItem.Params[j] = &item.Params[j]
+			}
+		}
+		if item.Frame != mavFrameMission {
+			qgcItem.Altitude = &item.Params[6]
+			qgcItem.AltitudeMode = 1 // Relative to home
+			if item.Frame == mavFrameGlobal {
+				qgcItem.AltitudeMode = 2 // Above sea level
+			}
+		}
+		plan.Mission.Items = append(plan.Mission.Items, qgcItem)
+	}
+
+	for _, zone := range m.Geofence {
+		polygon := qgcFencePolygon{Version: 1}
+		vertices := zone.Vertices
+		if len(vertices) > 1 && vertices[0] == vertices[len(vertices)-1] {
+			vertices = vertices[:len(vertices)-1]
+		}
+		for _, v := range vertices {
+			polygon.Polygon = append(polygon.Polygon, [2]float64{v.Y, v.X})
+		}
+		plan.GeoFence.Polygons = append(plan.GeoFence.Polygons, polygon)
+	}
+	return plan
+}
+
+// QGCPlanJSON returns the mission as an indented .plan file
+func (m Mission) QGCPlanJSON() ([]byte, error) {
+	return json.MarshalIndent(m.QGCPlan(), "", "    ")
+}
+
+// WaypointFile returns the mission in the QGC WPL 110 text format read by
+// ArduPilot and PX4 ground stations: the home position followed by one
+// tab-separated line per item
+// The format has no geofence; unused parameters are written as 0
+func (m Mission) WaypointFile() string {
+	var sb strings.Builder
+	sb.WriteString("QGC WPL 110\n")
+
+	line := func(index, current int, item missionItem) {
+		fields := []string{fmt.Sprint(index), fmt.Sprint(current), fmt.Sprint(item.Frame), fmt.Sprint(item.Command)}
+		for i, param := range item.Params {
+			if math.IsNaN(param) {
+				param = 0
+			}
+			precision := 6
+			if i == 4 || i == 5 {
+				precision = 8
+			}
+			fields = append(fields, fmt.Sprintf("%.*f", precision, param))
+		}
+		fields = append(fields, "1")
+		sb.WriteString(strings.Join(fields, "\t") + "\n")
+	}
+
+	line(0, 1, missionItem{Command: mavCmdNavWaypoint, Frame: mavFrameGlobal, Params: [7]float64{0, 0, 0, 0, m.Home.Y, m.Home.X, m.Home.Z}})
+	for i, item := range m.Items {
+		line(i+1, 0, item)
+	}
+	return sb.String()
+}
@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestMissionGeofence(t *testing.T) {
+	// The route takes off in the hole of a zone and flies east along latitude
+	// 52.05 at 40 m above home, below the floor of the zones it passes over
+	near := squareZone(5.46, 52.045, 0.01)
+	near.GID = 1
+	holed := squareZone(5.1, 52.0, 0.1)
+	holed.Holes = [][]Point{squareZone(5.12, 52.02, 0.06).Vertices}
+	holed.Floor = 60
+	holed.Ceiling = 120
+	holed.GID = 2
+	under := squareZone(5.25, 52.0, 0.1)
+	under.Floor = 60
+	under.Ceiling = 120
+	under.GID = 3
+	above := squareZone(5.4, 52.06, 0.02)
+	above.Floor = 60
+	above.Ceiling = 120
+	above.GID = 4
+	far := squareZone(6.0, 52.0, 0.1)
+	far.GID = 5
+
+	response := RouteResponse{
+		Success: true,
+		Path:    []Point{{X: 5.15, Y: 52.05, Z: 40}, {X: 5.3, Y: 52.05, Z: 40}, {X: 5.45, Y: 52.05, Z: 40}},
+	}
+	mission, err := NewMission(response, testPlanOptions([]Polygon{near, holed, under, above, far}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gids []int
+	for _, zone := range mission.Geofence {
+		gids = append(gids, zone.GID)
+	}
+	if want := []int{1}; !equalInts(sortedInts(gids), want) {
+		t.Errorf("geofence zones %v, want %v", gids, want)
+	}
+}
+
+func TestNewMissionChecksDefaultAltitude(t *testing.T) {
+	band := squareZone(5.0, 52.0, 0.1)
+	band.Floor = 50
+	band.Ceiling = 100
+	opts := testPlanOptions([]Polygon{band}, nil)
+
+	// Planned at ground level below the floor, exported at the default altitude
+	response := RouteResponse{Success: true, Path: []Point{{X: 5.02, Y: 52.05}, {X: 5.08, Y: 52.05}}}
+	if _, err := NewMission(response, opts); err == nil {
+		t.Error("mission at the default altitude inside a zone's band was exported")
+	}
+
+	response.Path[0].Z, response.Path[1].Z = 30, 30
+	if _, err := NewMission(response, opts); err != nil {
+		t.Errorf("mission below the floor refused: %v", err)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
+	"time"
 )
 
 // GeoJSON structures for parsing no-fly zone files
@@ -25,11 +27,22 @@ type GeoJSONFeatureCollection struct {
 }
 
 // loadNoFlyZonesFromFiles loads all GeoJSON files from the nfz-polygons directory
-func loadNoFlyZonesFromFiles() ([]Polygon, error) {
-	nfzDir := "nfz-polygons"
+// Each polygon gets the safety buffer configured for its category
+func loadNoFlyZonesFromFiles(buffers BufferConfig) ([]Polygon, error) {
+	allPolygons, err := loadZonesFromDir(noFlyZoneDir, buffers)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Total no-fly zones loaded: %d polygons\n", len(allPolygons))
+	return allPolygons, nil
+}
+
+// loadZonesFromDir loads all GeoJSON files from a directory
+func loadZonesFromDir(dir string, buffers BufferConfig) ([]Polygon, error) {
 	var allPolygons []Polygon
 
-	files, err := filepath.Glob(filepath.Join(nfzDir, "*.geojson"))
+	files, err := filepath.Glob(filepath.Join(dir, "*.geojson"))
 	if err != nil {
 		return nil, err
 	}
@@ -43,23 +56,38 @@ func loadNoFlyZonesFromFiles() ([]Polygon, error) {
 			continue
 		}
 
-		var featureCollection GeoJSONFeatureCollection
-		if err := json.Unmarshal(data, &featureCollection); err != nil {
+		polygons, err := parseZoneFeatureCollection(data, buffers)
+		if err != nil {
 			log.Printf("⚠️  Failed to parse %s: %v\n", file, err)
 			continue
 		}
+		allPolygons = append(allPolygons, polygons...)
 
-		polygonCount := 0
-		for _, feature := range featureCollection.Features {
-			polygons := parseGeoJSONGeometry(feature.Geometry)
-			allPolygons = append(allPolygons, polygons...)
-			polygonCount += len(polygons)
-		}
+		log.Printf("   ✅ Loaded %d polygons from %s\n", len(polygons), filepath.Base(file))
+	}
+
+	return allPolygons, nil
+}
 
-		log.Printf("   ✅ Loaded %d polygons from %s\n", polygonCount, filepath.Base(file))
+// parseZoneFeatureCollection converts a GeoJSON FeatureCollection to polygons
+// carrying their feature properties and configured safety buffer
+func parseZoneFeatureCollection(data []byte, buffers BufferConfig) ([]Polygon, error) {
+	var featureCollection GeoJSONFeatureCollection
+	if err := json.Unmarshal(data, &featureCollection); err != nil {
+		return nil, err
+	}
+
+	var allPolygons []Polygon
+	for _, feature := range featureCollection.Features {
+		polygons := parseGeoJSONGeometry(feature.Geometry)
+		buffer := buffers.BufferFor(feature.Properties)
+		for i := range polygons {
+			polygons[i].Buffer = buffer
+			applyZoneProperties(&polygons[i], feature.Properties)
+		}
+		allPolygons = append(allPolygons, polygons...)
 	}
 
-	log.Printf("Total no-fly zones loaded: %d polygons\n", len(allPolygons))
 	return allPolygons, nil
 }
 
@@ -74,14 +102,7 @@ func parseGeoJSONGeometry(geometry GeoJSONGeometry) []Polygon {
 			log.Printf("⚠️  Failed to parse Polygon coordinates: %v\n", err)
 			return polygons
 		}
-		// First ring is the outer boundary
-		if len(coords) > 0 {
-			polygon := Polygon{Vertices: make([]Point, 0, len(coords[0]))}
-			for _, coord := range coords[0] {
-				if len(coord) >= 2 {
-					polygon.Vertices = append(polygon.Vertices, Point{X: coord[0], Y: coord[1]})
-				}
-			}
+		if polygon, ok := polygonFromRings(coords); ok {
 			polygons = append(polygons, polygon)
 		}
 
@@ -92,13 +113,7 @@ func parseGeoJSONGeometry(geometry GeoJSONGeometry) []Polygon {
 			return polygons
 		}
 		for _, polyCoords := range coords {
-			if len(polyCoords) > 0 {
-				polygon := Polygon{Vertices: make([]Point, 0, len(polyCoords[0]))}
-				for _, coord := range polyCoords[0] {
-					if len(coord) >= 2 {
-						polygon.Vertices = append(polygon.Vertices, Point{X: coord[0], Y: coord[1]})
-					}
-				}
+			if polygon, ok := polygonFromRings(polyCoords); ok {
 				polygons = append(polygons, polygon)
 			}
 		}
@@ -106,3 +121,89 @@ func parseGeoJSONGeometry(geometry GeoJSONGeometry) []Polygon {
 
 	return polygons
 }
+
+// polygonFromRings converts GeoJSON polygon rings to our Polygon format
+// The first ring is the outer boundary, any following rings are holes
+func polygonFromRings(coords [][][]float64) (Polygon, bool) {
+	if len(coords) == 0 {
+		return Polygon{}, false
+	}
+
+	polygon := Polygon{Vertices: ringFromCoords(coords[0])}
+	for _, holeCoords := range coords[1:] {
+		hole := ringFromCoords(holeCoords)
+		if len(hole) >= 3 {
+			polygon.Holes = append(polygon.Holes, hole)
+		}
+	}
+
+	return polygon, true
+}
+
+// ringFromCoords converts a list of GeoJSON positions to points
+func ringFromCoords(coords [][]float64) []Point {
+	ring := make([]Point, 0, len(coords))
+	for _, coord := range coords {
+		if len(coord) >= 2 {
+			ring = append(ring, Point{X: coord[0], Y: coord[1]})
+		}
+	}
+	return ring
+}
+
+// applyZoneProperties copies the GeoJSON feature properties onto a polygon
+// and derives its zone category from the localtype
+func applyZoneProperties(polygon *Polygon, properties map[string]interface{}) {
+	polygon.Properties = properties
+	if gid, ok := properties["gid"].(float64); ok {
+		polygon.GID = int(gid)
+	}
+	if localType, ok := properties["localtype"].(string); ok {
+		polygon.LocalType = localType
+	}
+	if sourceTxt, ok := properties["source_txt"].(string); ok {
+		polygon.SourceTxt = sourceTxt
+	}
+	polygon.Category = categoryForLocalType(polygon.LocalType)
+
+	// Vertical limits in meters; zones without them apply from the ground up
+	if floor, ok := numberProperty(properties, "lower_limit", "floor"); ok {
+		polygon.Floor = floor
+	}
+	if ceiling, ok := numberProperty(properties, "upper_limit", "ceiling"); ok {
+		polygon.Ceiling = ceiling
+	}
+
+	// Validity window of temporary zones
+	polygon.ValidFrom = parseTimeProperty(properties, "valid_from")
+	polygon.ValidTo = parseTimeProperty(properties, "valid_to")
+}
+
+// numberProperty reads the first of the given keys holding a number or numeric string
+func numberProperty(properties map[string]interface{}, keys ...string) (float64, bool) {
+	for _, key := range keys {
+		switch value := properties[key].(type) {
+		case float64:
+			return value, true
+		case string:
+			if number, err := strconv.ParseFloat(value, 64); err == nil {
+				return number, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// parseTimeProperty reads an RFC 3339 timestamp property, returning nil if it is missing or invalid
+func parseTimeProperty(properties map[string]interface{}, key string) *time.Time {
+	value, ok := properties[key].(string)
+	if !ok {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		log.Printf("⚠️  Invalid %s %q: %v\n", key, value, err)
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,84 @@
+package main
+
+import "testing"
+
+func TestLoadZonesVerticalLimits(t *testing.T) {
+	zones, err := loadZonesFromDir("testdata", BufferConfig{DefaultMeters: 25})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		gid      int
+		parts    int
+		holes    int
+		category ZoneCategory
+		floor    float64
+		ceiling  float64
+	}{
+		{gid: 90001, parts: 1, holes: 1, category: ZoneHard, floor: 60, ceiling: 120},
+		{gid: 90002, parts: 2, holes: 0, category: ZoneRestricted, floor: 0, ceiling: 90},
+		{gid: 90003, parts: 1, holes: 0, category: ZoneHard, floor: 0, ceiling: 0},
+	}
+	for _, tt := range tests {
+		parts := 0
+		for _, zone := range zones {
+			if zone.GID != tt.gid {
+				continue
+			}
+			parts++
+			if len(zone.Holes) != tt.holes || zone.Category != tt.category || zone.Floor != tt.floor || zone.Ceiling != tt.ceiling {
+				t.Errorf("zone %d: holes %d, category %s, limits %v-%v; want %d, %s, %v-%v",
+					tt.gid, len(zone.Holes), zone.Category, zone.Floor, zone.Ceiling, tt.holes, tt.category, tt.floor, tt.ceiling)
+			}
+			if zone.Buffer != 25 {
+				t.Errorf("zone %d: buffer %v, want 25", tt.gid, zone.Buffer)
+			}
+		}
+		if parts != tt.parts {
+			t.Errorf("zone %d: %d polygons, want %d", tt.gid, parts, tt.parts)
+		}
+	}
+}
+
+func TestParseZoneHoles(t *testing.T) {
+	data := []byte(`{"type": "FeatureCollection", "features": [{
+		"type": "Feature",
+		"properties": {"gid": 1},
+		"geometry": {"type": "Polygon", "coordinates": [
+			[[5.0, 52.0], [5.1, 52.0], [5.1, 52.1], [5.0, 52.1], [5.0, 52.0]],
+			[[5.03, 52.03], [5.07, 52.03], [5.07, 52.07], [5.03, 52.07], [5.03, 52.03]]
+		]}
+	}]}`)
+	zones, err := parseZoneFeatureCollection(data, BufferConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zones) != 1 || len(zones[0].Holes) != 1 {
+		t.Fatalf("got %d zones, want 1 with 1 hole", len(zones))
+	}
+
+	tests := []struct {
+		name  string
+		point Point
+		want  bool
+	}{
+		{"inside outer ring", Point{X: 5.01, Y: 52.01}, true},
+		{"inside hole", Point{X: 5.05, Y: 52.05}, false},
+		{"outside", Point{X: 5.2, Y: 52.05}, false},
+	}
+	for _, tt := range tests {
+		if got := IsPointInPolygon(tt.point, zones[0]); got != tt.want {
+			t.Errorf("%s: IsPointInPolygon = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	crossing := LineSegment{P1: Point{X: 5.04, Y: 52.05}, P2: Point{X: 5.06, Y: 52.05}}
+	if DoesSegmentIntersectPolygon(crossing, zones[0]) {
+		t.Error("segment inside the hole intersects the zone")
+	}
+	leaving := LineSegment{P1: Point{X: 5.05, Y: 52.05}, P2: Point{X: 5.05, Y: 52.01}}
+	if !DoesSegmentIntersectPolygon(leaving, zones[0]) {
+		t.Error("segment leaving the hole does not intersect the zone")
+	}
+}
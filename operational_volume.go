@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// bufferArcStep is the largest angle between the points of a rounded corner
+const bufferArcStep = math.Pi / 12
+
+// OperationalVolume is the airspace a flight along a route is contained in:
+// the flight geography around the path and the contingency volume around it
+type OperationalVolume struct {
+	WidthMeters       float64
+	ContingencyMeters float64
+	FlightGeography   Polygon
+	ContingencyVolume Polygon
+	Conflicts         []AvoidedZone // No-fly zones overlapping the contingency volume
+}
+
+// NewOperationalVolume buffers the path by half the width for the flight
+// geography and by the contingency buffer on top for the contingency volume,
+// and checks the contingency volume against the zones that may not be entered
+// at the altitudes the path is flown
+func NewOperationalVolume(path []Point, width, contingency float64, opts PlanOptions, window *TimeWindow) OperationalVolume {
+	volume := OperationalVolume{
+		WidthMeters:       width,
+		ContingencyMeters: contingency,
+		FlightGeography:   bufferPath(path, width/2),
+		ContingencyVolume: bufferPath(path, width/2+contingency),
+	}
+
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, p := range path {
+		low, high = math.Min(low, p.Z), math.Max(high, p.Z)
+	}
+	for _, zones := range opts.hardZoneIndexes(window) {
+		for _, zone := range polygonConflicts(volume.ContingencyVolume, low, high, zones) {
+			polygon := zones.Zones[zone]
+			volume.Conflicts = append(volume.Conflicts, AvoidedZone{GID: polygon.GID, LocalType: polygon.LocalType, SourceTxt: polygon.SourceTxt})
+		}
+	}
+	return volume
+}
+
+// polygonConflicts returns the indices of the zones that overlap a polygon
+// between the altitudes low and high, including their safety buffers: zones
+// whose buffered area reaches one of its edges, contains it or lies inside it
+func polygonConflicts(polygon Polygon, low, high float64, zones *ZoneIndex) []int {
+	var conflicts []int
+	add := func(zone int) {
+		if !containsInt(conflicts, zone) {
+			conflicts = append(conflicts, zone)
+		}
+	}
+
+	for _, edge := range polygon.ringEdges() {
+		edge.P1.Z, edge.P2.Z = low, high
+		for _, zone := range zones.ZonesIntersectingSegment(edge) {
+			add(zone)
+		}
+	}
+	if len(polygon.Vertices) > 0 {
+		for _, zone := range zones.ZonesContainingPointBetween(polygon.Vertices[0], low, high) {
+			add(zone)
+		}
+	}
+
+	bounds := BoundingBoxOfPoints(polygon.Vertices)
+	zones.searchEnvelopes(bounds, low, high, func(zone int) bool {
+		vertices := zones.Zones[zone].Vertices
+		if len(vertices) > 0 && IsPointInPolygon(vertices[0], polygon) {
+			add(zone)
+		}
+		return true
+	})
+	return conflicts
+}
+
+// GeoJSON returns the flight geography and the contingency volume as Polygon features
+func (v OperationalVolume) GeoJSON() GeoJSONFeatureCollection {
+	feature := func(name string, polygon Polygon, bufferMeters float64) GeoJSONFeature {
+		ring := make([][]float64, len(polygon.Vertices))
+		for i, p := range polygon.Vertices {
+			ring[i] = []float64{p.X, p.Y}
+		}
+		data, _ := json.Marshal([][][]float64{ring})
+		return GeoJSONFeature{
+			Type:     "Feature",
+			Geometry: GeoJSONGeometry{Type: "Polygon", Coordinates: data},
+			Properties: map[string]interface{}{
+				"name":         name,
+				"bufferMeters": bufferMeters,
+			},
+		}
+	}
+
+	geography := feature("flightGeography", v.FlightGeography, v.WidthMeters/2)
+	geography.Properties["widthMeters"] = v.WidthMeters
+	contingency := feature("contingencyVolume", v.ContingencyVolume, v.WidthMeters/2+v.ContingencyMeters)
+	contingency.Properties["contingencyMeters"] = v.ContingencyMeters
+	contingency.Properties["clearOfNoFlyZones"] = len(v.Conflicts) == 0
+	if len(v.Conflicts) > 0 {
+		contingency.Properties["conflicts"] = v.Conflicts
+	}
+
+	return GeoJSONFeatureCollection{Type: "FeatureCollection", Features: []GeoJSONFeature{geography, contingency}}
+}
+
+// xy is a position in local meters
+type xy struct {
+	X, Y float64
+}
+
+// bufferPath returns the polygon containing every point within meters of the
+// path, with rounded corners and ends
+// The ring is closed and runs counterclockwise
+func bufferPath(path []Point, meters float64) Polygon {
+	refLat := 0.0
+	for _, p := range path {
+		refLat += p.Y
+	}
+	frame := localFrame{refLat: refLat / float64(len(path))}
+
+	var points []xy
+	for _, p := range path {
+		x, y := frame.toLocal(p)
+		if len(points) == 0 || x != points[len(points)-1].X || y != points[len(points)-1].Y {
+			points = append(points, xy{x, y})
+		}
+	}
+
+	var ring []xy
+	if len(points) == 1 {
+		ring = arc(points[0], meters, 0, 2*math.Pi)
+	} else {
+		reversed := make([]xy, len(points))
+		for i, p := range points {
+			reversed[len(points)-1-i] = p
+		}
+		ring = append(offsetSide(points, meters), offsetSide(reversed, meters)...)
+	}
+
+	polygon := Polygon{Vertices: make([]Point, 0, len(ring)+1)}
+	for i, p := range ring {
+		// Each side ends where the next one starts
+		if i > 0 && math.Hypot(p.X-ring[i-1].X, p.Y-ring[i-1].Y) < 1e-6 {
+			continue
+		}
+		polygon.Vertices = append(polygon.Vertices, frame.toPoint(p.X, p.Y, 0))
+	}
+	polygon.Vertices = append(polygon.Vertices, polygon.Vertices[0])
+	return polygon
+}
+
+// offsetSide returns the right-hand outline of a buffered polyline from its
+// start to its end, followed by the rounded cap around the end
+// Outer corners are rounded; inner corners use the intersection of the offset
+// edges, or pass through the corner where the legs are too short for it
+func offsetSide(points []xy, meters float64) []xy {
+	normal := func(i int) (float64, float64, float64) {
+		dx, dy := points[i+1].X-points[i].X, points[i+1].Y-points[i].Y
+		length := math.Hypot(dx, dy)
+		return dy / length, -dx / length, length
+	}
+
+	nx, ny, _ := normal(0)
+	side := []xy{{points[0].X + meters*nx, points[0].Y + meters*ny}}
+	for i := 1; i < len(points)-1; i++ {
+		inX, inY, inLength := normal(i - 1)
+		outX, outY, outLength := normal(i)
+		p := points[i]
+
+		// Positive on left turns, where the right-hand side is the outside of the corner
+		turn := math.Atan2(inX*outY-inY*outX, inX*outX+inY*outY)
+		if turn > 0 {
+			from := math.Atan2(inY, inX)
+			side = append(side, arc(p, meters, from, from+turn)...)
+			continue
+		}
+
+		if setback := meters * math.Tan(-turn/2); setback <= math.Min(inLength, outLength) {
+			scale := meters / (1 + inX*outX + inY*outY)
+			side = append(side, xy{p.X + scale*(inX+outX), p.Y + scale*(inY+outY)})
+		} else {
+			side = append(side, xy{p.X + meters*inX, p.Y + meters*inY}, p, xy{p.X + meters*outX, p.Y + meters*outY})
+		}
+	}
+
+	last := len(points) - 1
+	nx, ny, _ = normal(last - 1)
+	start := math.Atan2(ny, nx)
+	return append(side, arc(points[last], meters, start, start+math.Pi)...)
+}
+
+// arc returns points on a circle from one angle to another, counterclockwise
+func arc(center xy, radius, from, to float64) []xy {
+	steps := int(math.Ceil((to - from) / bufferArcStep))
+	points := make([]xy, 0, steps+1)
+	for i := 0; i <= steps; i++ {
+		angle := from + (to-from)*float64(i)/float64(max(steps, 1))
+		points = append(points, xy{center.X + radius*math.Cos(angle), center.Y + radius*math.Sin(angle)})
+	}
+	return points
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBufferPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path []Point
+	}{
+		{"single point", []Point{{X: 5.0, Y: 52.0}}},
+		{"straight", []Point{{X: 5.0, Y: 52.0}, {X: 5.05, Y: 52.02}}},
+		{"left turn", []Point{{X: 5.0, Y: 52.0}, {X: 5.05, Y: 52.0}, {X: 5.05, Y: 52.03}}},
+		{"right turn", []Point{{X: 5.0, Y: 52.0}, {X: 5.05, Y: 52.0}, {X: 5.05, Y: 51.97}}},
+		{"zigzag", []Point{{X: 5.0, Y: 52.0}, {X: 5.03, Y: 52.02}, {X: 5.06, Y: 52.0}, {X: 5.09, Y: 52.02}}},
+		{"repeated point", []Point{{X: 5.0, Y: 52.0}, {X: 5.0, Y: 52.0}, {X: 5.04, Y: 52.01}}},
+	}
+	const meters = 500.0
+	rng := rand.New(rand.NewSource(1))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer := bufferPath(tt.path, meters)
+			ring := buffer.Vertices
+			if ring[0] != ring[len(ring)-1] {
+				t.Fatal("ring is not closed")
+			}
+			area := 0.0
+			for i := 0; i < len(ring)-1; i++ {
+				area += ring[i].X*ring[i+1].Y - ring[i+1].X*ring[i].Y
+			}
+			if area <= 0 {
+				t.Errorf("ring runs clockwise")
+			}
+
+			// Points clearly within or beyond the buffer distance, allowing for
+			// the chords of the rounded corners
+			bounds := BoundingBoxOfPoints(tt.path).Expand(metersToDegrees(2*meters, 52))
+			for i := 0; i < 2000; i++ {
+				p := Point{
+					X: bounds.MinX + rng.Float64()*(bounds.MaxX-bounds.MinX),
+					Y: bounds.MinY + rng.Float64()*(bounds.MaxY-bounds.MinY),
+				}
+				dist := p.HorizontalDistanceMeters(tt.path[0])
+				for j := 0; j < len(tt.path)-1; j++ {
+					dist = min(dist, pointSegmentDistanceMeters(p, LineSegment{P1: tt.path[j], P2: tt.path[j+1]}))
+				}
+				inside := IsPointInPolygon(p, buffer)
+				if dist < 0.98*meters && !inside {
+					t.Fatalf("point %v at %.0f m is outside the buffer", p, dist)
+				}
+				if dist > 1.02*meters && inside {
+					t.Fatalf("point %v at %.0f m is inside the buffer", p, dist)
+				}
+			}
+		})
+	}
+}
+
+func TestPolygonConflicts(t *testing.T) {
+	// A 0.1 degree square and zones east of it
+	polygon := squareZone(5.0, 52.0, 0.1)
+	polygon.Vertices = append(polygon.Vertices, polygon.Vertices[0])
+
+	buffered := squareZone(5.102, 52.04, 0.02)
+	buffered.Buffer = 300
+	unbuffered := squareZone(5.102, 52.07, 0.02)
+	contained := squareZone(5.04, 52.04, 0.02)
+	containing := squareZone(4.9, 51.9, 0.3)
+	containing.Floor = 200
+	containing.Ceiling = 300
+	banded := squareZone(5.05, 52.0, 0.03)
+	banded.Floor = 60
+	banded.Ceiling = 120
+	zones := NewZoneIndex([]Polygon{buffered, unbuffered, contained, containing, banded})
+
+	tests := []struct {
+		name      string
+		low, high float64
+		want      []int
+	}{
+		{"below bands", 0, 50, []int{0, 2}},
+		{"through band", 50, 100, []int{0, 2, 4}},
+		{"inside containing zone", 250, 250, []int{0, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sortedInts(polygonConflicts(polygon, tt.low, tt.high, zones)); !equalInts(got, tt.want) {
+				t.Errorf("polygonConflicts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
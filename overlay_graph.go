@@ -0,0 +1,139 @@
+package main
+
+import "math"
+
+// PRMOverlay is a per-request view of a shared PRM graph that adds a virtual
+// start and end node without copying or modifying the base graph, so
+// concurrent requests can search the same PRMGraph safely
+// Blocked edges of the base graph are included when none of the zones they
+// pass through are blocking for this request
+type PRMOverlay struct {
+	base    *PRMGraph
+	zones   *ZoneIndex
+	costs   *CostModel
+	band    AltitudeBand // Base nodes outside the band are not used
+	StartID int
+	EndID   int
+
+	points       [2]Point      // Locations of the start and end nodes
+	virtualEdges [2][]int      // Base nodes connected to the start and end nodes
+	attached     map[int][]int // Base node ID -> virtual node IDs connected to it
+}
+
+// NewOverlay connects start and end points to the nearby nodes of the graph,
+// and to each other if the straight line between them is clear
+// StartID or EndID is -1 if the point could not be connected
+func (g *PRMGraph) NewOverlay(start, end Point, zones *ZoneIndex, costs *CostModel, band AltitudeBand) *PRMOverlay {
+	o := &PRMOverlay{
+		base:     g,
+		zones:    zones,
+		costs:    costs,
+		band:     band,
+		StartID:  len(g.Nodes),
+		EndID:    len(g.Nodes) + 1,
+		points:   [2]Point{start, end},
+		attached: make(map[int][]int),
+	}
+
+	for slot, point := range o.points {
+		// Points inside a blocking zone cannot be connected at all
+		if zones.IsPointBlocked(point) {
+			continue
+		}
+
+		virtualID := len(g.Nodes) + slot
+		for _, i := range g.attachmentCandidates(point) {
+			if !o.isUnblocked(g.Nodes[i].Zones) || !band.Contains(g.Nodes[i].Point.Z) {
+				continue
+			}
+
+			// Check if the edge enters any no-fly zone, also when it climbs or
+			// descends to the node's layer inside a zone's footprint
+			if IsPathClear(point, g.Nodes[i].Point, zones) {
+				o.virtualEdges[slot] = append(o.virtualEdges[slot], i)
+				o.attached[i] = append(o.attached[i], virtualID)
+			}
+		}
+	}
+
+	if IsPathClear(start, end, zones) {
+		o.virtualEdges[0] = append(o.virtualEdges[0], o.EndID)
+		o.virtualEdges[1] = append(o.virtualEdges[1], o.StartID)
+	}
+
+	if len(o.virtualEdges[0]) == 0 {
+		o.StartID = -1
+	}
+	if len(o.virtualEdges[1]) == 0 {
+		o.EndID = -1
+	}
+
+	return o
+}
+
+// NodePoint returns the location of a base or virtual node
+func (o *PRMOverlay) NodePoint(id int) Point {
+	if slot, ok := o.virtualSlot(id); ok {
+		return o.points[slot]
+	}
+	return o.base.Nodes[id].Point
+}
+
+// Neighbors returns the edges of a base or virtual node, including edges
+// to the virtual nodes
+func (o *PRMOverlay) Neighbors(id int) []Edge {
+	var neighborIDs, extra []int
+	if slot, ok := o.virtualSlot(id); ok {
+		neighborIDs = o.virtualEdges[slot]
+	} else {
+		neighborIDs = o.base.Nodes[id].Edges
+		extra = o.attached[id]
+		for _, blocked := range o.base.Nodes[id].BlockedEdges {
+			if o.isUnblocked(blocked.Zones) {
+				extra = append(extra, blocked.To)
+			}
+		}
+	}
+
+	from := o.NodePoint(id)
+	edges := make([]Edge, 0, len(neighborIDs)+len(extra))
+	for _, ids := range [][]int{neighborIDs, extra} {
+		for _, neighborID := range ids {
+			if _, virtual := o.virtualSlot(neighborID); !virtual && !o.band.Contains(o.NodePoint(neighborID).Z) {
+				continue
+			}
+			// Base graph edges do not know about zones added after the build
+			if !o.zones.isClearOfDynamicZones(LineSegment{P1: from, P2: o.NodePoint(neighborID)}) {
+				continue
+			}
+			// Legs the drone cannot fly against the wind are left out
+			cost := o.costs.EdgeCost(from, o.NodePoint(neighborID))
+			if math.IsInf(cost, 1) {
+				continue
+			}
+			edges = append(edges, Edge{To: neighborID, Cost: cost})
+		}
+	}
+	return edges
+}
+
+// Heuristic returns the cost model's lower bound of the cost between two points
+func (o *PRMOverlay) Heuristic(from, to Point) float64 {
+	return o.costs.Heuristic(from, to)
+}
+
+// isUnblocked reports whether none of the given zones are blocking for this request
+func (o *PRMOverlay) isUnblocked(zones []int) bool {
+	for _, zone := range zones {
+		if o.zones.applies(zone) {
+			return false
+		}
+	}
+	return true
+}
+
+// virtualSlot maps a node ID to its index in points/virtualEdges if it is virtual
+func (o *PRMOverlay) virtualSlot(id int) (int, bool) {
+	slot := id - len(o.base.Nodes)
+	return slot, slot == 0 || slot == 1
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// gridGraph returns a PRM graph at ground level with a node every 0.01 degrees
+// from (5, 52) to (5.3, 52.2), connected to its eight neighbours
+func gridGraph(zones *ZoneIndex) *PRMGraph {
+	graph := &PRMGraph{ConnectionRadius: 0.015, AltitudeLayers: []float64{0}, ZoneSignature: zones.Signature()}
+	graph.BoundingBox.MinLon, graph.BoundingBox.MaxLon = 5, 5.3
+	graph.BoundingBox.MinLat, graph.BoundingBox.MaxLat = 52, 52.2
+	for i := 0; i <= 30; i++ {
+		for j := 0; j <= 20; j++ {
+			point := Point{X: 5 + float64(i)*0.01, Y: 52 + float64(j)*0.01}
+			graph.Nodes = append(graph.Nodes, PRMNode{ID: len(graph.Nodes), Point: point, Edges: make([]int, 0), Zones: zones.ZonesContainingPoint(point)})
+		}
+	}
+	graph.BuildIndex()
+	for i, node := range graph.Nodes {
+		for _, j := range graph.index.RadiusSearch(node.Point, graph.ConnectionRadius) {
+			if j > i {
+				graph.connect(i, j, edgeBlockers(node, graph.Nodes[j], LineSegment{P1: node.Point, P2: graph.Nodes[j].Point}, zones))
+			}
+		}
+	}
+	return graph
+}
+
+func TestNewOverlayLeavesBaseGraphUnchanged(t *testing.T) {
+	zones := NewZoneIndex([]Polygon{squareZone(5.12, 52.06, 0.06)})
+	graph := gridGraph(zones)
+	before := make([]PRMNode, len(graph.Nodes))
+	for i, node := range graph.Nodes {
+		before[i] = node
+		before[i].Edges = append(node.Edges[:0:0], node.Edges...)
+	}
+
+	// Concurrent requests search the same base graph
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			start := Point{X: 5.005 + float64(i)*0.001, Y: 52.09}
+			end := Point{X: 5.285, Y: 52.09}
+			overlay := graph.NewOverlay(start, end, zones, &CostModel{}, AltitudeBand{})
+			if overlay.StartID != len(graph.Nodes) || overlay.EndID != len(graph.Nodes)+1 {
+				t.Errorf("virtual nodes %d and %d, want %d and %d", overlay.StartID, overlay.EndID, len(graph.Nodes), len(graph.Nodes)+1)
+				return
+			}
+			path, found := AStarPathOnGraph(overlay, overlay.StartID, overlay.EndID)
+			if !found || path[0] != start || path[len(path)-1] != end {
+				t.Errorf("request %d: no path from start to end", i)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if !reflect.DeepEqual(graph.Nodes, before) {
+		t.Error("overlays modified the base graph")
+	}
+}
+
+func TestNewOverlayDirectEdge(t *testing.T) {
+	zones := NewZoneIndex([]Polygon{squareZone(5.12, 52.06, 0.06)})
+	graph := gridGraph(zones)
+
+	tests := []struct {
+		name   string
+		end    Point
+		direct bool
+	}{
+		{"clear line", Point{X: 5.1, Y: 52.02}, true},
+		{"line through zone", Point{X: 5.285, Y: 52.09}, false},
+	}
+	for _, tt := range tests {
+		overlay := graph.NewOverlay(Point{X: 5.005, Y: 52.09}, tt.end, zones, &CostModel{}, AltitudeBand{})
+		direct := false
+		for _, edge := range overlay.Neighbors(overlay.StartID) {
+			direct = direct || edge.To == overlay.EndID
+		}
+		if direct != tt.direct {
+			t.Errorf("%s: direct edge %v, want %v", tt.name, direct, tt.direct)
+		}
+	}
+}
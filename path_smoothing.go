@@ -0,0 +1,107 @@
+package main
+
+import (
+	"math/rand"
+)
+
+// PathLengthMeters returns the total length of a path in meters
+func PathLengthMeters(path []Point) float64 {
+	length := 0.0
+	for i := 0; i < len(path)-1; i++ {
+		length += path[i].DistanceMeters(path[i+1])
+	}
+	return length
+}
+
+// ShortcutPath greedily removes waypoints by connecting each waypoint to the
+// farthest later waypoint that is still reachable in a straight line without
+// increasing the path cost
+func ShortcutPath(path []Point, zones *ZoneIndex, costs *CostModel) []Point {
+	if len(path) <= 2 {
+		return path
+	}
+
+	smoothed := []Point{path[0]}
+	i := 0
+	for i < len(path)-1 {
+		// Fall back to the next waypoint, which is always reachable on a valid path
+		next := i + 1
+		for j := len(path) - 1; j > i+1; j-- {
+			if IsPathClear(path[i], path[j], zones) &&
+				costs.EdgeCost(path[i], path[j]) <= costs.PathCost(path[i:j+1]) {
+				next = j
+				break
+			}
+		}
+		smoothed = append(smoothed, path[next])
+		i = next
+	}
+
+	return smoothed
+}
+
+// maxSmoothIterations caps the random shortcut attempts a request may ask for,
+// as they are repeated for every leg, alternative and tour matrix entry
+const maxSmoothIterations = 1000
+
+// RandomShortcutPath repeatedly picks two random points along the path and
+// replaces the section between them with a straight line if it is clear and cheaper
+func RandomShortcutPath(path []Point, zones *ZoneIndex, costs *CostModel, iterations int, rng *rand.Rand) []Point {
+	if len(path) <= 2 {
+		return path
+	}
+
+	for iter := 0; iter < iterations; iter++ {
+		total := PathLengthMeters(path)
+		if total == 0 {
+			break
+		}
+
+		d1 := rng.Float64() * total
+		d2 := rng.Float64() * total
+		if d1 > d2 {
+			d1, d2 = d2, d1
+		}
+
+		seg1, a := pointAlongPath(path, d1)
+		seg2, b := pointAlongPath(path, d2)
+		if seg1 == seg2 {
+			continue
+		}
+
+		// Cost of the section being replaced
+		section := append([]Point{a}, path[seg1+1:seg2+1]...)
+		section = append(section, b)
+		if costs.EdgeCost(a, b) >= costs.PathCost(section) {
+			continue
+		}
+		if !IsPathClear(a, b, zones) {
+			continue
+		}
+
+		shortened := make([]Point, 0, len(path))
+		shortened = append(shortened, path[:seg1+1]...)
+		shortened = append(shortened, a, b)
+		shortened = append(shortened, path[seg2+1:]...)
+		path = shortened
+	}
+
+	return ShortcutPath(path, zones, costs)
+}
+
+// pointAlongPath returns the segment index and the point located dist meters along the path
+func pointAlongPath(path []Point, dist float64) (int, Point) {
+	for i := 0; i < len(path)-1; i++ {
+		segLength := path[i].DistanceMeters(path[i+1])
+		if dist <= segLength && segLength > 0 {
+			t := dist / segLength
+			return i, Point{
+				X: path[i].X + t*(path[i+1].X-path[i].X),
+				Y: path[i].Y + t*(path[i+1].Y-path[i].Y),
+				Z: path[i].Z + t*(path[i+1].Z-path[i].Z),
+			}
+		}
+		dist -= segLength
+	}
+	return len(path) - 2, path[len(path)-1]
+}
@@ -0,0 +1,31 @@
+package main
+
+// Permit describes the zones an operator is authorized to fly in for a request
+// Permitted zones are ignored by collision checks and restricted zone penalties
+type Permit struct {
+	Name string `json:"name,omitempty"`
+	// Categories lists GeoJSON localtype or source_txt values that are allowed
+	Categories []string `json:"categories,omitempty"`
+	// GIDs lists the gid of individual zones that are allowed
+	GIDs []int `json:"gids,omitempty"`
+}
+
+// Allows reports whether the permit authorizes flight inside a zone
+func (p *Permit) Allows(zone *Polygon) bool {
+	if p == nil {
+		return false
+	}
+	for _, category := range p.Categories {
+		if category == zone.LocalType || category == zone.SourceTxt {
+			return true
+		}
+	}
+	if zone.GID != 0 {
+		for _, gid := range p.GIDs {
+			if gid == zone.GID {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,480 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// PlanOptions holds the per-request settings used when planning a path
+type PlanOptions struct {
+	Zones             *ZoneIndex        // All loaded no-fly zones
+	RestrictedPenalty float64           // Extra cost per meter flown inside restricted zones
+	SmoothIterations  int               // Random shortcut attempts after greedy smoothing
+	Permit            *Permit           // Zones the operator may enter, nil for none
+	Altitude          AltitudeBand      // Altitudes the route may use between start and end
+	Terrain           *TerrainClearance // Terrain legs must clear, nil to ignore terrain
+	Kinematics        *Kinematics       // Turn constraints of fixed-wing drones, nil for multirotors
+	ChargingSites     []ChargingSite    // Sites where routes beyond the vehicle's range may recharge
+	Wind              *WindField        // Wind that changes ground speeds, nil for still air
+	Avoid             *Corridor         // Corridor of earlier routes to stay out of, nil for none
+
+	TemporaryZones *ZoneIndex     // Time-limited zones that are not part of the PRM graph
+	Departure      time.Time      // Departure time used for temporary zones and the ETA
+	Vehicle        VehicleProfile // Speeds and overheads used to estimate flight times
+
+	window TimeWindow // Flight window the active temporary zones are selected for
+}
+
+// RoutePlan is the result of planning a path between two points
+type RoutePlan struct {
+	Path                     []Point
+	AltitudeProfile          []ProfilePoint // Flight altitude over the terrain, nil without terrain
+	Trajectory               []Point        // Densified Dubins trajectory, nil without kinematics
+	TrajectoryMeters         float64        // Length of the trajectory
+	AirMeters                float64        // Flown distance in still-air meters, longer with headwind
+	Timing                   *FlightTiming  // Estimated flight time and ETA
+	Energy                   *EnergySummary // Battery use per leg, nil without a battery model
+	ChargingStops            []ChargingStop // Sites the drone lands to recharge, in flight order
+	Legs                     []RouteLeg     // Sections between consecutive requested stops
+	StopIndexes              []int          // Index in Path of every requested stop
+	ZonesAvoided             []AvoidedZone  // No-fly zones on the straight lines between stops
+	energyLegs               []EnergyLeg    // Flights between stops and charging sites
+	trajectoryWaypoints      []int          // Index in the trajectory of every waypoint
+	Success                  bool
+	Message                  string
+	Direct                   bool // Straight line without using the PRM graph
+	RawWaypoints             int  // Waypoints returned by A* before smoothing
+	DistanceMeters           float64
+	RestrictedDistanceMeters float64 // Part of the distance flown inside restricted zones
+	Cost                     float64 // Distance plus restricted zone penalties
+	FlightWindow             *TimeWindow
+	ActiveTemporaryZones     int // Temporary zones in force during the flight window
+}
+
+// maxFlightWindowAttempts limits how often a path is replanned because the
+// estimated flight window grew and more temporary zones became active
+const maxFlightWindowAttempts = 4
+
+// flightWindowMargin pads the estimated flight time when the window is extended
+const flightWindowMargin = 1.2
+
+// BlockingZones returns the view of the zone index containing the zones that may not be entered
+func (o PlanOptions) BlockingZones() *ZoneIndex {
+	filter := func(zone *Polygon) bool {
+		return isHardZone(zone) && !o.Permit.Allows(zone)
+	}
+	return o.withTemporaryZones(o.Zones.WithFilter(filter), filter).WithTerrain(o.Terrain)
+}
+
+// hardZoneIndexes returns views of the permanent zones and of the temporary
+// zones active during the flight window that may not be entered, without terrain
+func (o PlanOptions) hardZoneIndexes(window *TimeWindow) []*ZoneIndex {
+	return o.zoneIndexes(window, isHardZone)
+}
+
+// restrictedZoneIndexes returns views of the permanent and temporary restricted
+// zones active during the flight window that are not permitted
+func (o PlanOptions) restrictedZoneIndexes(window *TimeWindow) []*ZoneIndex {
+	return o.zoneIndexes(window, isRestrictedZone)
+}
+
+// zoneIndexes returns views of the permanent zones and the temporary zones
+// active during the flight window accepted by category, leaving out the zones
+// the permit allows
+func (o PlanOptions) zoneIndexes(window *TimeWindow, category func(*Polygon) bool) []*ZoneIndex {
+	filter := func(zone *Polygon) bool {
+		return category(zone) && !o.Permit.Allows(zone)
+	}
+	indexes := []*ZoneIndex{o.Zones.WithFilter(filter)}
+	if o.TemporaryZones != nil && len(o.TemporaryZones.Zones) > 0 && window != nil {
+		indexes = append(indexes, o.TemporaryZones.WithFilter(func(zone *Polygon) bool {
+			return filter(zone) && zone.IsActiveDuring(window.Start, window.End)
+		}))
+	}
+	return indexes
+}
+
+// CostModel returns the edge cost model for these options
+func (o PlanOptions) CostModel() *CostModel {
+	filter := func(zone *Polygon) bool {
+		return isRestrictedZone(zone) && !o.Permit.Allows(zone)
+	}
+	return &CostModel{
+		Restricted:        o.withTemporaryZones(o.Zones.WithFilter(filter), filter),
+		RestrictedPenalty: o.RestrictedPenalty,
+		Wind:              o.Wind,
+		Airspeed:          o.Vehicle.CruiseSpeedMps,
+		Avoid:             o.Avoid,
+		AvoidPenalty:      alternativePenalty,
+	}
+}
+
+// withTemporaryZones adds the temporary zones accepted by filter that are
+// active during the flight window to a zone index view
+func (o PlanOptions) withTemporaryZones(zones *ZoneIndex, filter func(*Polygon) bool) *ZoneIndex {
+	if o.TemporaryZones == nil || len(o.TemporaryZones.Zones) == 0 {
+		return zones
+	}
+	return zones.WithDynamicZones(o.TemporaryZones.WithFilter(func(zone *Polygon) bool {
+		return filter(zone) && zone.IsActiveDuring(o.window.Start, o.window.End)
+	}))
+}
+
+// AvoidedZone identifies a no-fly zone a route flies around
+type AvoidedZone struct {
+	GID       int    `json:"gid"`
+	LocalType string `json:"localtype,omitempty"`
+	SourceTxt string `json:"sourceTxt,omitempty"`
+}
+
+// avoidedZones returns the no-fly zones blocking the straight lines between
+// consecutive stops, which a route between them flies around
+func (o PlanOptions) avoidedZones(stops []Point) []AvoidedZone {
+	zones := o.Zones.WithFilter(func(zone *Polygon) bool {
+		return isHardZone(zone) && !o.Permit.Allows(zone)
+	})
+
+	var seen []int
+	var avoided []AvoidedZone
+	for i := 0; i+1 < len(stops); i++ {
+		for _, zone := range zones.ZonesIntersectingSegment(LineSegment{P1: stops[i], P2: stops[i+1]}) {
+			if containsInt(seen, zone) {
+				continue
+			}
+			seen = append(seen, zone)
+			polygon := zones.Zones[zone]
+			avoided = append(avoided, AvoidedZone{GID: polygon.GID, LocalType: polygon.LocalType, SourceTxt: polygon.SourceTxt})
+		}
+	}
+	return avoided
+}
+
+// activeTemporaryZones counts the temporary zones in force during the flight window
+func (o PlanOptions) activeTemporaryZones() int {
+	if o.TemporaryZones == nil {
+		return 0
+	}
+	count := 0
+	for _, zone := range o.TemporaryZones.Zones {
+		if zone.IsActiveDuring(o.window.Start, o.window.End) {
+			count++
+		}
+	}
+	return count
+}
+
+// PlanRoute finds a collision-free route through ordered stops by stitching
+// the paths planned between consecutive stops, and estimates its flight time
+// The battery carries over from stop to stop; charging stops are inserted into
+// a leg when the remaining range does not reach the next stop
+func PlanRoute(graph *PRMGraph, stops []Point, opts PlanOptions) RoutePlan {
+	var plan RoutePlan
+	remaining := opts.Vehicle.RangeMeters()
+	legOpts := opts
+
+	for i := 0; i+1 < len(stops); i++ {
+		from, to := stopName(i, len(stops)), stopName(i+1, len(stops))
+		if len(stops) > 2 {
+			log.Printf("🧭 Leg %d/%d: %s -> %s\n", i+1, len(stops)-1, from, to)
+		}
+
+		// Fixed-wing legs leave a via point on the heading the previous leg arrived with
+		if opts.Kinematics != nil {
+			kinematics := *opts.Kinematics
+			if i > 0 {
+				kinematics.StartHeading = nil
+				if heading, ok := finalHeading(plan.Trajectory, plan.Path); ok {
+					kinematics.StartHeading = &heading
+				}
+			}
+			if i+2 < len(stops) {
+				kinematics.EndHeading = nil
+			}
+			legOpts.Kinematics = &kinematics
+		}
+
+		leg := planLeg(graph, stops[i], stops[i+1], legOpts)
+		if leg.Success && leg.AirMeters > remaining {
+			log.Printf("🔋 Leg of %.2f km (%.2f km in still air) exceeds the remaining range of %.2f km - planning charging stops...\n",
+				leg.FlownMeters()/1000, leg.AirMeters/1000, remaining/1000)
+			leg = planWithChargingStops(graph, stops[i], stops[i+1], legOpts, remaining, from, to)
+		} else if leg.Success {
+			leg.energyLegs = []EnergyLeg{
+				{From: from, To: to, DistanceMeters: leg.FlownMeters(), AirDistanceMeters: leg.AirMeters},
+			}
+		}
+		if !leg.Success {
+			if len(stops) > 2 {
+				leg.Message = fmt.Sprintf("Leg %d (%s to %s): %s", i+1, from, to, leg.Message)
+			}
+			return leg
+		}
+
+		if len(leg.ChargingStops) > 0 {
+			remaining = opts.Vehicle.RangeMeters() - leg.energyLegs[len(leg.energyLegs)-1].AirDistanceMeters
+		} else {
+			remaining -= leg.AirMeters
+		}
+
+		startIndex := max(len(plan.Path)-1, 0)
+		plan = plan.appendLeg(leg)
+		plan.Legs = append(plan.Legs, RouteLeg{
+			From:           from,
+			To:             to,
+			DistanceMeters: leg.DistanceMeters,
+			StartIndex:     startIndex,
+			EndIndex:       len(plan.Path) - 1,
+			ChargingStops:  len(leg.ChargingStops),
+		})
+
+		// Temporary zones for the next leg are selected from its departure, once
+		// the leg is flown including its charging stops
+		legOpts.Departure = leg.flightTiming(opts.Vehicle, opts.Wind, legOpts.Departure).ETA
+	}
+
+	plan.StopIndexes = []int{0}
+	for _, leg := range plan.Legs {
+		plan.StopIndexes = append(plan.StopIndexes, leg.EndIndex)
+	}
+	if len(plan.ChargingStops) > 0 {
+		plan.Message = fmt.Sprintf("Route with %d charging stops", len(plan.ChargingStops))
+	}
+	plan.ZonesAvoided = opts.avoidedZones(stops)
+
+	if opts.Vehicle.HasBattery() {
+		plan.Energy = opts.Vehicle.EnergySummary(plan.energyLegs)
+	}
+	plan.Timing = plan.flightTiming(opts.Vehicle, opts.Wind, opts.Departure)
+	log.Printf("⏱️  Flight time %.1f min (%s), ETA %s\n",
+		plan.Timing.TotalSeconds/60, opts.Vehicle.Name, plan.Timing.ETA.Format(time.RFC3339))
+	return plan
+}
+
+// planLeg finds a collision-free path between two points, computes its
+// altitude profile over the terrain and, with kinematics, its flyable trajectory
+// The profile must keep the terrain clearance and stay out of the zones' vertical limits
+func planLeg(graph *PRMGraph, start, end Point, opts PlanOptions) RoutePlan {
+	plan := planPathInFlightWindow(graph, start, end, opts)
+	if !plan.Success {
+		return plan
+	}
+	if plan.FlightWindow != nil {
+		opts.window = *plan.FlightWindow
+	}
+
+	if opts.Kinematics != nil {
+		log.Printf("✈️  Connecting %d waypoints with Dubins curves (turn radius %.0f m)...\n",
+			len(plan.Path), opts.Kinematics.TurnRadius)
+		trajectory, waypoints, ok := opts.Kinematics.Trajectory(plan.Path, opts.BlockingZones())
+		if !ok {
+			log.Println("❌ No clear Dubins trajectory found")
+			return RoutePlan{Message: "Could not connect the waypoints with turns clear of no-fly zones"}
+		}
+		plan.Trajectory = trajectory
+		plan.trajectoryWaypoints = waypoints
+		plan.TrajectoryMeters = PathLengthMeters(trajectory)
+		log.Printf("   ✅ Trajectory with %d points (%.2f km)\n", len(trajectory), plan.TrajectoryMeters/1000)
+	}
+
+	profile, err := opts.Terrain.AltitudeProfile(plan.Path)
+	if err != nil {
+		log.Printf("❌ %v\n", err)
+		return RoutePlan{Message: fmt.Sprintf("Could not keep the terrain clearance: %v", err)}
+	}
+	if leg := firstBlockedLeg(plan.Path, profile, opts.BlockingZones()); leg >= 0 {
+		log.Printf("❌ Flight altitude over the terrain enters a no-fly zone on leg %d\n", leg+1)
+		return RoutePlan{Message: fmt.Sprintf("The flight altitude over the terrain enters a no-fly zone on leg %d", leg+1)}
+	}
+	plan.AltitudeProfile = profile
+	plan.AirMeters = plan.FlownMeters()
+	if opts.Wind != nil {
+		flown := plan.Path
+		if plan.Trajectory != nil {
+			flown = plan.Trajectory
+		}
+		plan.AirMeters = opts.Wind.PathAirMeters(flown, opts.Vehicle.CruiseSpeedMps)
+		log.Printf("🌬️  %.2f km flown, %.2f km in still air\n", plan.FlownMeters()/1000, plan.AirMeters/1000)
+	}
+	return plan
+}
+
+// RouteLeg is the section of a route between two consecutive requested stops
+type RouteLeg struct {
+	From           string  `json:"from"`
+	To             string  `json:"to"`
+	DistanceMeters float64 `json:"distanceMeters"`
+	StartIndex     int     `json:"startIndex"` // Index in the path where the leg starts
+	EndIndex       int     `json:"endIndex"`   // Index in the path where the leg ends
+	ChargingStops  int     `json:"chargingStops,omitempty"`
+}
+
+// stopName names a requested stop by its position in the route
+func stopName(i, count int) string {
+	switch i {
+	case 0:
+		return "start"
+	case count - 1:
+		return "end"
+	}
+	return fmt.Sprintf("via %d", i)
+}
+
+// FlownMeters returns the distance actually flown, following the trajectory when there is one
+func (plan RoutePlan) FlownMeters() float64 {
+	if plan.Trajectory != nil {
+		return plan.TrajectoryMeters
+	}
+	return plan.DistanceMeters
+}
+
+// flightTiming estimates the timing of the plan, following the trajectory
+// when there is one, the altitude profile when the terrain is known and the
+// ground speed with the wind when there is a wind field
+func (plan RoutePlan) flightTiming(vehicle VehicleProfile, wind *WindField, departure time.Time) *FlightTiming {
+	altitude := func(i int) float64 {
+		if plan.AltitudeProfile != nil {
+			return plan.AltitudeProfile[i].AltitudeMeters
+		}
+		return plan.Path[i].Z
+	}
+
+	legMeters := make([]float64, len(plan.Path)-1)
+	legClimbs := make([]float64, len(plan.Path)-1)
+	for i := range legMeters {
+		if plan.Trajectory != nil {
+			from, to := plan.trajectoryWaypoints[i], plan.trajectoryWaypoints[i+1]
+			legMeters[i] = wind.PathAirMeters(plan.Trajectory[from:to+1], vehicle.CruiseSpeedMps)
+		} else {
+			legMeters[i] = wind.AirMeters(plan.Path[i], plan.Path[i+1], vehicle.CruiseSpeedMps)
+		}
+		legClimbs[i] = altitude(i+1) - altitude(i)
+	}
+
+	stops := make([]int, len(plan.ChargingStops))
+	for i, stop := range plan.ChargingStops {
+		stops[i] = stop.WaypointIndex
+	}
+	return vehicle.Timing(legMeters, legClimbs, stops, departure)
+}
+
+// planPathInFlightWindow finds a collision-free path between two points that avoids the
+// temporary zones active during the estimated flight window
+// The window is estimated from the straight-line distance first, and the path
+// is replanned with a longer window while the planned flight takes longer
+func planPathInFlightWindow(graph *PRMGraph, start, end Point, opts PlanOptions) RoutePlan {
+	if opts.TemporaryZones == nil || len(opts.TemporaryZones.Zones) == 0 {
+		return planPathInWindow(graph, start, end, opts)
+	}
+
+	opts.window = TimeWindow{
+		Start: opts.Departure,
+		End:   opts.Departure.Add(opts.Vehicle.EstimateDuration(start.DistanceMeters(end))),
+	}
+
+	for attempt := 1; attempt <= maxFlightWindowAttempts; attempt++ {
+		log.Printf("⏳ Flight window %s - %s (%d temporary zones active)\n",
+			opts.window.Start.Format(time.RFC3339), opts.window.End.Format(time.RFC3339), opts.activeTemporaryZones())
+
+		plan := planPathInWindow(graph, start, end, opts)
+		if !plan.Success {
+			return plan
+		}
+
+		arrival := opts.Departure.Add(opts.Vehicle.EstimateDuration(plan.DistanceMeters))
+		if !arrival.After(opts.window.End) {
+			plan.FlightWindow = &TimeWindow{Start: opts.Departure, End: arrival}
+			plan.ActiveTemporaryZones = opts.activeTemporaryZones()
+			return plan
+		}
+
+		opts.window.End = opts.Departure.Add(opts.Vehicle.EstimateDuration(plan.DistanceMeters * flightWindowMargin))
+	}
+
+	log.Println("❌ Flight window did not converge")
+	return RoutePlan{Message: "Could not find a path that avoids temporary zones for its whole flight window"}
+}
+
+// planPathInWindow finds a collision-free path between two points, using a straight
+// line when it is clear and does not cross restricted zones, and the PRM graph otherwise
+func planPathInWindow(graph *PRMGraph, start, end Point, opts PlanOptions) RoutePlan {
+	blocking := opts.BlockingZones()
+	costs := opts.CostModel()
+
+	// First, check if a straight line path is possible (no obstacles)
+	log.Println("🔍 Checking if straight line path is possible...")
+	straightLineClear := IsPathClear(start, end, blocking)
+	direct := []Point{start, end}
+
+	if straightLineClear && !math.IsInf(costs.PathCost(direct), 1) && opts.Avoid == nil &&
+		(costs.RestrictedLengthMeters(direct) == 0 || graph == nil) {
+		log.Println("✅ Straight line path is clear!")
+		return newRoutePlan(direct, 2, costs, true, "Direct straight line path (no obstacles)")
+	}
+
+	if straightLineClear {
+		log.Println("⚠️  Straight line crosses restricted zones - comparing with PRM graph paths...")
+	} else {
+		log.Println("⚠️  Straight line blocked - using PRM graph pathfinding...")
+	}
+
+	if graph == nil {
+		log.Println("❌ PRM graph not available")
+		return RoutePlan{Message: "PRM graph not built"}
+	}
+
+	// Create a per-request overlay with start and end points connected
+	log.Println("🔗 Connecting start and end points to graph...")
+	overlay := graph.NewOverlay(start, end, blocking, costs, opts.Altitude)
+	startNodeID, endNodeID := overlay.StartID, overlay.EndID
+
+	if startNodeID == -1 || endNodeID == -1 {
+		log.Println("❌ Could not connect start or end point to graph")
+		return RoutePlan{Message: "Could not connect start or end point to the graph (possibly blocked by no-fly zones)"}
+	}
+
+	log.Printf("   ✅ Start connected as node %d\n", startNodeID)
+	log.Printf("   ✅ End connected as node %d\n", endNodeID)
+
+	// Run A* directly on the overlay
+	log.Println("🔍 Running A* on PRM graph...")
+	path, success := AStarPathOnGraph(overlay, startNodeID, endNodeID)
+	if !success {
+		log.Println("❌ No path found on PRM graph")
+		return RoutePlan{Message: "No path found on PRM graph"}
+	}
+
+	// Shortcut the zig-zagging PRM path
+	rawWaypoints := len(path)
+	rawDistance := PathLengthMeters(path)
+	path = ShortcutPath(path, blocking, costs)
+	if opts.SmoothIterations > 0 {
+		rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+		path = RandomShortcutPath(path, blocking, costs, opts.SmoothIterations, rng)
+	}
+
+	plan := newRoutePlan(path, rawWaypoints, costs, len(path) == 2, "")
+	log.Printf("   Smoothed %d -> %d waypoints (%.2f km -> %.2f km)\n",
+		rawWaypoints, len(path), rawDistance/1000, plan.DistanceMeters/1000)
+	if plan.RestrictedDistanceMeters > 0 {
+		log.Printf("   Restricted zones: %.2f km flown inside\n", plan.RestrictedDistanceMeters/1000)
+	}
+
+	return plan
+}
+
+// newRoutePlan builds a successful plan and computes its distance and cost
+func newRoutePlan(path []Point, rawWaypoints int, costs *CostModel, direct bool, message string) RoutePlan {
+	return RoutePlan{
+		Path:                     path,
+		Success:                  true,
+		Message:                  message,
+		Direct:                   direct,
+		RawWaypoints:             rawWaypoints,
+		DistanceMeters:           PathLengthMeters(path),
+		RestrictedDistanceMeters: costs.RestrictedLengthMeters(path),
+		Cost:                     costs.PathCost(path),
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// testPlanOptions returns planning options against the given permanent and
+// temporary zones, departing at a fixed time
+func testPlanOptions(zones, temporary []Polygon) PlanOptions {
+	return PlanOptions{
+		Zones:             NewZoneIndex(zones),
+		TemporaryZones:    NewZoneIndex(temporary),
+		RestrictedPenalty: 2,
+		Departure:         time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC),
+		Vehicle:           DefaultVehicleProfile,
+	}
+}
+
+func TestPlanRouteLegDepartsAfterChargingStops(t *testing.T) {
+	opts := testPlanOptions(nil, nil)
+	opts.Vehicle.BatteryWh = 150
+	opts.Vehicle.ConsumptionWhPerKm = 10
+	opts.Vehicle.ReservePercent = 0
+	opts.ChargingSites = []ChargingSite{{ID: "middle", Point: Point{X: 5.15, Y: 52.0}}}
+
+	// A temporary zone on the second leg that expires before the first leg,
+	// including its charging stop, is flown
+	validFrom := opts.Departure
+	validTo := opts.Departure.Add(40 * time.Minute)
+	zone := squareZone(5.32, 51.99, 0.01)
+	zone.ValidFrom, zone.ValidTo = &validFrom, &validTo
+	opts.TemporaryZones = NewZoneIndex([]Polygon{zone})
+
+	stops := []Point{{X: 5.0, Y: 52.0}, {X: 5.3, Y: 52.0}, {X: 5.35, Y: 52.0}}
+	plan := PlanRoute(nil, stops, opts)
+	if !plan.Success {
+		t.Fatalf("no route: %s", plan.Message)
+	}
+	if len(plan.Legs) != 2 || plan.Legs[0].ChargingStops != 1 {
+		t.Fatalf("legs %+v, want 2 legs with a charging stop on the first", plan.Legs)
+	}
+}
+
+func TestPlanRoutePermitUnlocksBlockedEdges(t *testing.T) {
+	// A wall across the whole graph and a smaller zone on the straight line
+	wall := squareZone(5.14, 51.9, 0.02)
+	wall.Vertices[2].Y, wall.Vertices[3].Y = 52.3, 52.3
+	wall.GID = 7
+	obstacle := squareZone(5.04, 52.08, 0.02)
+	obstacle.GID = 8
+	opts := testPlanOptions([]Polygon{wall, obstacle}, nil)
+	graph := gridGraph(opts.Zones.WithFilter(isHardZone))
+	stops := []Point{{X: 5.02, Y: 52.09}, {X: 5.28, Y: 52.09}}
+
+	if plan := PlanRoute(graph, stops, opts); plan.Success {
+		t.Fatal("route found through the wall without a permit")
+	}
+
+	opts.Permit = &Permit{GIDs: []int{7}}
+	plan := PlanRoute(graph, stops, opts)
+	if !plan.Success {
+		t.Fatalf("no route with a permit for the wall: %s", plan.Message)
+	}
+	if plan.Direct {
+		t.Error("route flies the straight line through the unpermitted zone")
+	}
+	crossesWall := false
+	for i := 0; i < len(plan.Path)-1; i++ {
+		crossesWall = crossesWall || !IsPathClear(plan.Path[i], plan.Path[i+1], NewZoneIndex([]Polygon{wall}))
+		if !IsPathClear(plan.Path[i], plan.Path[i+1], NewZoneIndex([]Polygon{obstacle})) {
+			t.Fatalf("leg %d enters the unpermitted zone", i)
+		}
+	}
+	if !crossesWall {
+		t.Error("route does not cross the permitted wall")
+	}
+}
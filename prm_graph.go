@@ -7,6 +7,7 @@ import (
 	"math"
 	"math/rand"
 	"os"
+	"sort"
 	"time"
 )
 
@@ -15,6 +16,17 @@ type PRMNode struct {
 	ID    int   `json:"id"`
 	Point Point `json:"point"`
 	Edges []int `json:"edges"` // IDs of connected nodes
+
+	// Zones containing the node; it is only usable when all of them are permitted
+	Zones []int `json:"zones,omitempty"`
+	// Edges that cross no-fly zones and are only usable when all of them are permitted
+	BlockedEdges []BlockedEdge `json:"blockedEdges,omitempty"`
+}
+
+// BlockedEdge is a connection that passes through one or more hard no-fly zones
+type BlockedEdge struct {
+	To    int   `json:"to"`
+	Zones []int `json:"zones"` // Indices of the zones the edge passes through
 }
 
 // PRMGraph represents a pre-computed probabilistic roadmap
@@ -26,10 +38,20 @@ type PRMGraph struct {
 		MinLon float64 `json:"minLon"`
 		MaxLon float64 `json:"maxLon"`
 	} `json:"boundingBox"`
-	NumSamples       int     `json:"numSamples"`
-	ConnectionRadius float64 `json:"connectionRadius"` // in degrees
+	NumSamples       int       `json:"numSamples"`
+	ConnectionRadius float64   `json:"connectionRadius"`           // in degrees
+	ZoneSignature    string    `json:"zoneSignature"`              // Signature of the no-fly zones the graph was built against
+	Version          int       `json:"version"`                    // prmGraphVersion the graph was saved with
+	AltitudeLayers   []float64 `json:"altitudeLayers"`             // Flight altitudes in meters every sample is placed at
+	TerrainSignature string    `json:"terrainSignature,omitempty"` // Signature of the terrain clearance the graph was built against
+
+	index *KDTree // Neighbor index over node points, rebuilt on build/load
 }
 
+// prmGraphVersion is bumped whenever the saved graph format changes, so
+// older prm_graph.json files are rebuilt instead of loaded
+const prmGraphVersion = 3
+
 // Netherlands bounding box (approximate)
 const (
 	NetherlandsMinLat = 50.75 // South (Limburg)
@@ -39,16 +61,27 @@ const (
 )
 
 // BuildPRMGraph creates a probabilistic roadmap with random sampling
-// Excludes edges that intersect with no-fly zone polygons
-func BuildPRMGraph(numSamples int, connectionRadius float64, noFlyZones []Polygon) *PRMGraph {
+// Every sample is placed at each altitude layer, with horizontal edges within a
+// layer and vertical edges between adjacent layers of the same sample
+// Edges that intersect no-fly zone polygons are kept separately as blocked
+// edges, annotated with the zones they cross, so permits can unlock them per request
+func BuildPRMGraph(numSamples int, connectionRadius float64, zones *ZoneIndex, altitudeLayers []float64) *PRMGraph {
 	startTime := time.Now()
+	if len(altitudeLayers) == 0 {
+		altitudeLayers = []float64{0}
+	}
 	log.Printf("🗺️  Building PRM graph with %d samples...\n", numSamples)
-	log.Printf("   No-fly zones: %d polygons\n", len(noFlyZones))
+	log.Printf("   No-fly zones: %d polygons\n", len(zones.Zones))
+	log.Printf("   Altitude layers: %v meters\n", altitudeLayers)
 
 	graph := &PRMGraph{
 		Nodes:            make([]PRMNode, 0, numSamples),
 		NumSamples:       numSamples,
 		ConnectionRadius: connectionRadius,
+		ZoneSignature:    zones.Signature(),
+		Version:          prmGraphVersion,
+		AltitudeLayers:   altitudeLayers,
+		TerrainSignature: zones.terrain.Signature(),
 	}
 
 	// Set bounding box to Netherlands
@@ -60,184 +93,190 @@ func BuildPRMGraph(numSamples int, connectionRadius float64, noFlyZones []Polygo
 	// Initialize random number generator
 	rand.Seed(time.Now().UnixNano())
 
-	// Step 1: Random sampling within bounding box (filter out points inside no-fly zones)
+	// Step 1: Random sampling within bounding box (points inside no-fly zones are
+	// kept in addition to numSamples free points, marked with their zones)
+	// A sample is free if it is outside all zones in at least one layer
 	log.Println("   Generating random samples...")
-	validSamples := 0
+	freeSamples := 0
+	samplesInZones := 0
 	attempts := 0
 	maxAttempts := numSamples * 10 // Try up to 10x the desired samples
 
-	for validSamples < numSamples && attempts < maxAttempts {
+	for freeSamples < numSamples && attempts < maxAttempts {
 		attempts++
 		lat := NetherlandsMinLat + rand.Float64()*(NetherlandsMaxLat-NetherlandsMinLat)
 		lon := NetherlandsMinLon + rand.Float64()*(NetherlandsMaxLon-NetherlandsMinLon)
-		point := Point{X: lon, Y: lat}
-
-		// Check if point is inside any no-fly zone
-		insideNoFlyZone := false
-		for _, polygon := range noFlyZones {
-			if IsPointInPolygon(point, polygon) {
-				insideNoFlyZone = true
-				break
-			}
-		}
-
-		if !insideNoFlyZone {
+		free := false
+		for _, altitude := range altitudeLayers {
+			point := Point{X: lon, Y: lat, Z: altitude}
 			node := PRMNode{
-				ID:    validSamples,
+				ID:    len(graph.Nodes),
 				Point: point,
 				Edges: make([]int, 0),
+				Zones: zones.ZonesContainingPoint(point),
 			}
+			free = free || len(node.Zones) == 0
 			graph.Nodes = append(graph.Nodes, node)
-			validSamples++
+		}
+
+		if free {
+			freeSamples++
+		} else {
+			samplesInZones++
 		}
 	}
 
-	if validSamples < numSamples {
-		log.Printf("   ⚠️  Only generated %d valid samples (requested %d)\n", validSamples, numSamples)
+	if freeSamples < numSamples {
+		log.Printf("   ⚠️  Only generated %d valid samples (requested %d)\n", freeSamples, numSamples)
 	}
+	log.Printf("   ℹ️  %d samples inside no-fly zones (only usable with a permit)\n", samplesInZones)
 
 	// Step 2: Connect nearby nodes (only if edge doesn't intersect no-fly zones)
 	log.Printf("   Connecting nodes (radius: %.4f degrees ≈ %.0f meters)...\n",
 		connectionRadius, connectionRadius*111000)
 
+	graph.BuildIndex()
+
 	edgeCount := 0
 	rejectedEdges := 0
+	terrainEdges := 0
 
 	for i := 0; i < len(graph.Nodes); i++ {
-		for j := i + 1; j < len(graph.Nodes); j++ {
-			dist := distance(graph.Nodes[i].Point, graph.Nodes[j].Point)
-
-			if dist <= connectionRadius {
-				// Check if edge intersects any no-fly zone
-				edgeClear := true
-				for _, polygon := range noFlyZones {
-					if DoesEdgeIntersectPolygon(graph.Nodes[i].Point, graph.Nodes[j].Point, polygon) {
-						edgeClear = false
-						rejectedEdges++
-						break
-					}
-				}
-
-				if edgeClear {
-					// Add bidirectional edge
-					graph.Nodes[i].Edges = append(graph.Nodes[i].Edges, j)
-					graph.Nodes[j].Edges = append(graph.Nodes[j].Edges, i)
-					edgeCount++
-				}
+		neighbors := graph.index.RadiusSearch(graph.Nodes[i].Point, connectionRadius)
+		sort.Ints(neighbors)
+
+		for _, j := range neighbors {
+			// Horizontal edges only connect nodes within the same layer
+			if j <= i || graph.Nodes[j].Point.Z != graph.Nodes[i].Point.Z {
+				continue
 			}
+
+			// Legs over terrain that varies too much are dropped, no permit can unlock them
+			seg := LineSegment{P1: graph.Nodes[i].Point, P2: graph.Nodes[j].Point}
+			if !zones.terrain.IsSegmentFlyable(seg) {
+				terrainEdges++
+				continue
+			}
+
+			// Check if edge intersects any no-fly zone
+			if blockers := edgeBlockers(graph.Nodes[i], graph.Nodes[j], seg, zones); len(blockers) > 0 {
+				graph.connect(i, j, blockers)
+				rejectedEdges++
+			} else {
+				graph.connect(i, j, nil)
+				edgeCount++
+			}
+		}
+	}
+
+	// Step 3: Connect adjacent altitude layers of each sample with vertical edges
+	// Nodes of a sample are stored consecutively, one per layer
+	for i := 0; i+1 < len(graph.Nodes); i++ {
+		if (i+1)%len(altitudeLayers) == 0 {
+			continue // Top layer of this sample
+		}
+		blockers := graph.verticalEdgeBlockers(i, i+1, zones)
+		graph.connect(i, i+1, blockers)
+		if len(blockers) > 0 {
+			rejectedEdges++
+		} else {
+			edgeCount++
 		}
 	}
 
 	elapsed := time.Since(startTime)
 	log.Printf("   ✅ PRM graph built: %d nodes, %d edges\n", len(graph.Nodes), edgeCount)
 	if rejectedEdges > 0 {
-		log.Printf("   ℹ️  Rejected %d edges due to no-fly zone intersections\n", rejectedEdges)
+		log.Printf("   ℹ️  Blocked %d edges due to no-fly zone intersections\n", rejectedEdges)
+	}
+	if terrainEdges > 0 {
+		log.Printf("   ℹ️  Dropped %d edges that cannot keep terrain clearance below the AGL ceiling\n", terrainEdges)
 	}
 	log.Printf("   ⏱️  Build time: %.2f seconds\n", elapsed.Seconds())
 
 	return graph
 }
 
-// distance calculates Euclidean distance in degrees (simple for connection check)
-func distance(p1, p2 Point) float64 {
-	dx := p1.X - p2.X
-	dy := p1.Y - p2.Y
-	return math.Sqrt(dx*dx + dy*dy)
+// edgeBlockers returns the zones an edge between two nodes passes through,
+// including the zones containing either node
+func edgeBlockers(a, b PRMNode, seg LineSegment, zones *ZoneIndex) []int {
+	return mergeZoneLists(zones.ZonesIntersectingSegment(seg), a.Zones, b.Zones)
 }
 
-// DoesEdgeIntersectPolygon checks if an edge between two points intersects a polygon
-func DoesEdgeIntersectPolygon(p1, p2 Point, polygon Polygon) bool {
-	seg := LineSegment{P1: p1, P2: p2}
-	return DoesSegmentIntersectPolygon(seg, polygon)
+// verticalEdgeBlockers returns the zones the vertical edge between two layers
+// of a sample passes through, including the zones containing either node
+func (g *PRMGraph) verticalEdgeBlockers(lower, upper int, zones *ZoneIndex) []int {
+	a, b := g.Nodes[lower], g.Nodes[upper]
+	blockers := zones.ZonesContainingPointBetween(a.Point, a.Point.Z, b.Point.Z)
+	return mergeZoneLists(blockers, a.Zones, b.Zones)
 }
 
-// CreateGraphWithStartEnd creates a temporary graph with start and end points connected
-// Returns the modified graph and the node IDs for start and end points
-func (g *PRMGraph) CreateGraphWithStartEnd(start, end Point, noFlyZones []Polygon) (*PRMGraph, int, int) {
-	// Create a copy of the graph with additional nodes for start and end
-	tempGraph := &PRMGraph{
-		BoundingBox:      g.BoundingBox,
-		NumSamples:       g.NumSamples,
-		ConnectionRadius: g.ConnectionRadius,
-		Nodes:            make([]PRMNode, len(g.Nodes)+2), // +2 for start and end
+// connect adds a bidirectional edge between two nodes, as a blocked edge when
+// it passes through zones
+func (g *PRMGraph) connect(i, j int, blockers []int) {
+	if len(blockers) > 0 {
+		g.Nodes[i].BlockedEdges = append(g.Nodes[i].BlockedEdges, BlockedEdge{To: j, Zones: blockers})
+		g.Nodes[j].BlockedEdges = append(g.Nodes[j].BlockedEdges, BlockedEdge{To: i, Zones: blockers})
+		return
 	}
+	g.Nodes[i].Edges = append(g.Nodes[i].Edges, j)
+	g.Nodes[j].Edges = append(g.Nodes[j].Edges, i)
+}
 
-	// Copy all existing nodes
-	copy(tempGraph.Nodes, g.Nodes)
-
-	// Add start point as a new node
-	startNodeID := len(g.Nodes)
-	tempGraph.Nodes[startNodeID] = PRMNode{
-		ID:    startNodeID,
-		Point: start,
-		Edges: make([]int, 0),
-	}
-
-	// Add end point as a new node
-	endNodeID := len(g.Nodes) + 1
-	tempGraph.Nodes[endNodeID] = PRMNode{
-		ID:    endNodeID,
-		Point: end,
-		Edges: make([]int, 0),
-	}
-
-	// Connect start point to nearby nodes within connection radius
-	startConnected := false
-	for i := 0; i < len(g.Nodes); i++ {
-		dist := distance(start, g.Nodes[i].Point)
-		if dist <= g.ConnectionRadius {
-			// Check if edge intersects any no-fly zone
-			edgeClear := true
-			for _, polygon := range noFlyZones {
-				if DoesEdgeIntersectPolygon(start, g.Nodes[i].Point, polygon) {
-					edgeClear = false
-					break
-				}
-			}
-
-			if edgeClear {
-				// Add bidirectional edge
-				tempGraph.Nodes[startNodeID].Edges = append(tempGraph.Nodes[startNodeID].Edges, i)
-				tempGraph.Nodes[i].Edges = append(tempGraph.Nodes[i].Edges, startNodeID)
-				startConnected = true
+// mergeZoneLists returns the sorted union of zone index lists
+func mergeZoneLists(lists ...[]int) []int {
+	var merged []int
+	for _, list := range lists {
+		for _, zone := range list {
+			if !containsInt(merged, zone) {
+				merged = append(merged, zone)
 			}
 		}
 	}
+	sort.Ints(merged)
+	return merged
+}
 
-	// Connect end point to nearby nodes within connection radius
-	endConnected := false
-	for i := 0; i < len(g.Nodes); i++ {
-		dist := distance(end, g.Nodes[i].Point)
-		if dist <= g.ConnectionRadius {
-			// Check if edge intersects any no-fly zone
-			edgeClear := true
-			for _, polygon := range noFlyZones {
-				if DoesEdgeIntersectPolygon(end, g.Nodes[i].Point, polygon) {
-					edgeClear = false
-					break
-				}
-			}
-
-			if edgeClear {
-				// Add bidirectional edge
-				tempGraph.Nodes[endNodeID].Edges = append(tempGraph.Nodes[endNodeID].Edges, i)
-				tempGraph.Nodes[i].Edges = append(tempGraph.Nodes[i].Edges, endNodeID)
-				endConnected = true
-			}
+// containsInt checks if a slice contains a value
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
 		}
 	}
+	return false
+}
 
-	// Return -1 for node IDs if connection failed
-	if !startConnected {
-		log.Println("   ⚠️  Could not connect start point to any graph node")
-		return tempGraph, -1, endNodeID
-	}
-	if !endConnected {
-		log.Println("   ⚠️  Could not connect end point to any graph node")
-		return tempGraph, startNodeID, -1
+// distance calculates Euclidean distance in degrees (simple for connection check)
+func distance(p1, p2 Point) float64 {
+	dx := p1.X - p2.X
+	dy := p1.Y - p2.Y
+	return math.Sqrt(dx*dx + dy*dy)
+}
+
+// BuildIndex (re)builds the k-d tree used for neighbor queries on the graph nodes
+func (g *PRMGraph) BuildIndex() {
+	points := make([]Point, len(g.Nodes))
+	for i, node := range g.Nodes {
+		points[i] = node.Point
 	}
+	g.index = NewKDTree(points)
+}
 
-	return tempGraph, startNodeID, endNodeID
+// attachFallbackNeighbors is the number of nearest nodes tried when no node
+// within the connection radius can be reached from a start or end point
+const attachFallbackNeighbors = 10
+
+// attachmentCandidates returns the IDs of the graph nodes a new point should be
+// connected to: all nodes within the connection radius, or the nearest nodes
+// if none are in range
+func (g *PRMGraph) attachmentCandidates(point Point) []int {
+	candidates := g.index.RadiusSearch(point, g.ConnectionRadius)
+	if len(candidates) == 0 {
+		candidates = g.index.KNearest(point, attachFallbackNeighbors)
+	}
+	sort.Ints(candidates)
+	return candidates
 }
 
 // SavePRMGraph serializes and saves the graph to a JSON file
@@ -273,6 +312,8 @@ func LoadPRMGraph(filename string) (*PRMGraph, error) {
 		return nil, fmt.Errorf("failed to unmarshal graph: %w", err)
 	}
 
+	graph.BuildIndex()
+
 	log.Printf("   ✅ Graph loaded: %d nodes\n", len(graph.Nodes))
 	return &graph, nil
 }
@@ -304,52 +345,3 @@ func (g *PRMGraph) GetGraphAsLineStrings() [][]Point {
 
 	return lines
 }
-
-// FindNearestNode finds the closest node to a given point
-func (g *PRMGraph) FindNearestNode(point Point) (int, float64) {
-	if len(g.Nodes) == 0 {
-		return -1, math.MaxFloat64
-	}
-
-	nearestID := 0
-	minDist := point.Distance(g.Nodes[0].Point)
-
-	for i := 1; i < len(g.Nodes); i++ {
-		dist := point.Distance(g.Nodes[i].Point)
-		if dist < minDist {
-			minDist = dist
-			nearestID = i
-		}
-	}
-
-	return nearestID, minDist
-}
-
-// ConvertToGraph converts PRM graph to the existing Graph structure for A*
-func (g *PRMGraph) ConvertToGraph() *Graph {
-	graph := &Graph{
-		Nodes: make(map[int]Point),
-		Edges: make(map[int][]Edge),
-	}
-
-	// Add all nodes
-	for _, node := range g.Nodes {
-		graph.Nodes[node.ID] = node.Point
-	}
-
-	// Add all edges
-	for _, node := range g.Nodes {
-		edges := make([]Edge, 0, len(node.Edges))
-		for _, neighborID := range node.Edges {
-			neighbor := g.Nodes[neighborID]
-			cost := node.Point.Distance(neighbor.Point)
-			edges = append(edges, Edge{
-				To:   neighborID,
-				Cost: cost,
-			})
-		}
-		graph.Edges[node.ID] = edges
-	}
-
-	return graph
-}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// resampleAttempts is how many random positions are tried for each sample
+// that replaces one lost to a new zone
+const resampleAttempts = 10
+
+// PRMRepair summarizes an incremental repair of the PRM graph after the
+// no-fly zones changed
+type PRMRepair struct {
+	ZonesAdded   int     `json:"zonesAdded"`
+	ZonesRemoved int     `json:"zonesRemoved"`
+	NodesUpdated int     `json:"nodesUpdated"` // Nodes whose containing zones were recomputed
+	EdgesUpdated int     `json:"edgesUpdated"` // Edges whose blocking zones were recomputed
+	SamplesAdded int     `json:"samplesAdded"` // Free samples added around new zones
+	Seconds      float64 `json:"seconds"`
+}
+
+// matchZones maps every old zone to the index of an identical new zone, or -1
+// when it was removed, and returns the indices of the new zones without an
+// old counterpart
+// Zones are identical when the geometry, buffer, vertical limits and category
+// the graph depends on are the same
+func matchZones(oldZones, newZones []Polygon) ([]int, []int) {
+	bySignature := make(map[string][]int)
+	for i, zone := range newZones {
+		signature := zoneSignature(zone)
+		bySignature[signature] = append(bySignature[signature], i)
+	}
+
+	mapping := make([]int, len(oldZones))
+	for i, zone := range oldZones {
+		signature := zoneSignature(zone)
+		if candidates := bySignature[signature]; len(candidates) > 0 {
+			mapping[i] = candidates[0]
+			bySignature[signature] = candidates[1:]
+		} else {
+			mapping[i] = -1
+		}
+	}
+
+	var added []int
+	for _, indices := range bySignature {
+		added = append(added, indices...)
+	}
+	sort.Ints(added)
+	return mapping, added
+}
+
+// RepairPRMGraph returns a copy of the graph updated from the old to the new
+// no-fly zones, both views of the hard zones: zone references are renumbered,
+// nodes and edges near added or removed zones are checked again, and samples
+// that lost their last free layer to an added zone are replaced by new samples
+// around it
+// The old graph is left untouched so requests planning on it can finish
+func RepairPRMGraph(graph *PRMGraph, oldZones, newZones *ZoneIndex) (*PRMGraph, PRMRepair) {
+	startTime := time.Now()
+	var repair PRMRepair
+	mapping, added := matchZones(oldZones.Zones, newZones.Zones)
+
+	// Collision checks can only change within the envelopes of the changed hard zones
+	var changed, addedEnvelopes []BoundingBox
+	for i, j := range mapping {
+		if j < 0 {
+			repair.ZonesRemoved++
+			if oldZones.applies(i) {
+				changed = append(changed, zoneEnvelope(oldZones.Zones[i]))
+			}
+		}
+	}
+	for _, j := range added {
+		repair.ZonesAdded++
+		if newZones.applies(j) {
+			envelope := zoneEnvelope(newZones.Zones[j])
+			changed = append(changed, envelope)
+			addedEnvelopes = append(addedEnvelopes, envelope)
+		}
+	}
+	log.Printf("🔧 Repairing PRM graph: %d zones added, %d removed, %d hard zone areas to recheck\n",
+		repair.ZonesAdded, repair.ZonesRemoved, len(changed))
+
+	changedTree := NewRTree(changed)
+	isChanged := func(box BoundingBox) bool {
+		found := false
+		changedTree.Search(box, func(int) bool {
+			found = true
+			return false
+		})
+		return found
+	}
+	renumber := func(zones []int) []int {
+		var result []int
+		for _, zone := range zones {
+			if mapping[zone] >= 0 {
+				result = append(result, mapping[zone])
+			}
+		}
+		sort.Ints(result)
+		return result
+	}
+
+	repaired := *graph
+	repaired.ZoneSignature = newZones.Signature()
+	repaired.Nodes = make([]PRMNode, len(graph.Nodes))
+	for i, node := range graph.Nodes {
+		repaired.Nodes[i] = PRMNode{ID: node.ID, Point: node.Point, Edges: make([]int, 0), Zones: renumber(node.Zones)}
+		if isChanged(BoundingBox{MinX: node.Point.X, MinY: node.Point.Y, MaxX: node.Point.X, MaxY: node.Point.Y}) {
+			repaired.Nodes[i].Zones = newZones.ZonesContainingPoint(node.Point)
+			repair.NodesUpdated++
+		}
+	}
+
+	// Keep every edge, rechecking those that pass through a changed area
+	relink := func(i, j int, blockers []int) {
+		seg := LineSegment{P1: repaired.Nodes[i].Point, P2: repaired.Nodes[j].Point}
+		if isChanged(segmentBoundingBox(seg)) {
+			blockers = repaired.edgeBlockersBetween(i, j, newZones)
+			repair.EdgesUpdated++
+		}
+		repaired.connect(i, j, blockers)
+	}
+	for i, node := range graph.Nodes {
+		for _, j := range node.Edges {
+			if j > i {
+				relink(i, j, nil)
+			}
+		}
+		for _, blocked := range node.BlockedEdges {
+			if blocked.To > i {
+				relink(i, blocked.To, renumber(blocked.Zones))
+			}
+		}
+	}
+
+	// Replace the samples a new zone covers in every layer with samples within
+	// half the connection radius of it, keeping the density of free samples
+	// around the zone
+	layers := max(len(graph.AltitudeLayers), 1)
+	firstNew := len(repaired.Nodes)
+	for sample := 0; sample < firstNew/layers; sample++ {
+		if !isFreeSample(graph.Nodes, sample, layers) || isFreeSample(repaired.Nodes, sample, layers) {
+			continue
+		}
+		p := repaired.Nodes[sample*layers].Point
+		for _, envelope := range addedEnvelopes {
+			if envelope.Contains(p) {
+				if repaired.addFreeSample(envelope.Expand(repaired.ConnectionRadius/2), newZones) {
+					repair.SamplesAdded++
+				}
+				break
+			}
+		}
+	}
+
+	repaired.BuildIndex()
+	for i := firstNew; i < len(repaired.Nodes); i++ {
+		node := repaired.Nodes[i]
+		for _, j := range repaired.index.RadiusSearch(node.Point, repaired.ConnectionRadius) {
+			// Pairs of new nodes are connected once, from the lower ID
+			if j == i || (j >= firstNew && j < i) || repaired.Nodes[j].Point.Z != node.Point.Z {
+				continue
+			}
+			seg := LineSegment{P1: node.Point, P2: repaired.Nodes[j].Point}
+			if !newZones.terrain.IsSegmentFlyable(seg) {
+				continue
+			}
+			repaired.connect(i, j, repaired.edgeBlockersBetween(i, j, newZones))
+		}
+		if (i+1)%layers != 0 {
+			repaired.connect(i, i+1, repaired.verticalEdgeBlockers(i, i+1, newZones))
+		}
+	}
+
+	repair.Seconds = time.Since(startTime).Seconds()
+	log.Printf("   ✅ Rechecked %d nodes and %d edges, added %d samples in %.2f seconds\n",
+		repair.NodesUpdated, repair.EdgesUpdated, repair.SamplesAdded, repair.Seconds)
+	return &repaired, repair
+}
+
+// edgeBlockersBetween returns the zones the edge between two nodes passes
+// through, for both horizontal and vertical edges
+func (g *PRMGraph) edgeBlockersBetween(i, j int, zones *ZoneIndex) []int {
+	a, b := g.Nodes[i], g.Nodes[j]
+	if a.Point.X == b.Point.X && a.Point.Y == b.Point.Y {
+		if a.Point.Z > b.Point.Z {
+			i, j = j, i
+		}
+		return g.verticalEdgeBlockers(i, j, zones)
+	}
+	return edgeBlockers(a, b, LineSegment{P1: a.Point, P2: b.Point}, zones)
+}
+
+// isFreeSample reports whether a sample, stored as one node per layer, is
+// outside all zones in at least one layer
+func isFreeSample(nodes []PRMNode, sample, layers int) bool {
+	for _, node := range nodes[sample*layers : (sample+1)*layers] {
+		if len(node.Zones) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// addFreeSample places a random sample inside the box and the graph's
+// bounding box, at every altitude layer, and adds it unconnected when it is
+// free in at least one layer
+func (g *PRMGraph) addFreeSample(box BoundingBox, zones *ZoneIndex) bool {
+	minLon, maxLon := math.Max(box.MinX, g.BoundingBox.MinLon), math.Min(box.MaxX, g.BoundingBox.MaxLon)
+	minLat, maxLat := math.Max(box.MinY, g.BoundingBox.MinLat), math.Min(box.MaxY, g.BoundingBox.MaxLat)
+	if minLon > maxLon || minLat > maxLat {
+		return false
+	}
+	altitudeLayers := g.AltitudeLayers
+	if len(altitudeLayers) == 0 {
+		altitudeLayers = []float64{0}
+	}
+
+	for attempt := 0; attempt < resampleAttempts; attempt++ {
+		lon := minLon + rand.Float64()*(maxLon-minLon)
+		lat := minLat + rand.Float64()*(maxLat-minLat)
+
+		nodes := make([]PRMNode, len(altitudeLayers))
+		free := false
+		for i, altitude := range altitudeLayers {
+			point := Point{X: lon, Y: lat, Z: altitude}
+			nodes[i] = PRMNode{ID: len(g.Nodes) + i, Point: point, Edges: make([]int, 0), Zones: zones.ZonesContainingPoint(point)}
+			free = free || len(nodes[i].Zones) == 0
+		}
+		if free {
+			g.Nodes = append(g.Nodes, nodes...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchZones(t *testing.T) {
+	a, b, c := squareZone(5.0, 52.0, 0.1), squareZone(5.2, 52.0, 0.1), squareZone(5.4, 52.0, 0.1)
+	raised := a
+	raised.Floor = 60
+	buffered := b
+	buffered.Buffer = 100
+	restricted := c
+	restricted.Category = ZoneRestricted
+	renamed := a
+	renamed.GID = 42
+	renamed.SourceTxt = "renamed"
+
+	tests := []struct {
+		name        string
+		old, new    []Polygon
+		wantMapping []int
+		wantAdded   []int
+	}{
+		{"unchanged", []Polygon{a, b, c}, []Polygon{a, b, c}, []int{0, 1, 2}, nil},
+		{"reordered", []Polygon{a, b, c}, []Polygon{c, a, b}, []int{1, 2, 0}, nil},
+		{"removed", []Polygon{a, b, c}, []Polygon{a, c}, []int{0, -1, 1}, nil},
+		{"added", []Polygon{a, c}, []Polygon{a, b, c}, []int{0, 2}, []int{1}},
+		{"duplicates", []Polygon{a, a}, []Polygon{a, b, a, a}, []int{0, 2}, []int{1, 3}},
+		{"vertical limits changed", []Polygon{a}, []Polygon{raised}, []int{-1}, []int{0}},
+		{"buffer changed", []Polygon{b}, []Polygon{buffered}, []int{-1}, []int{0}},
+		{"category changed", []Polygon{c}, []Polygon{restricted}, []int{-1}, []int{0}},
+		{"only attributes changed", []Polygon{a}, []Polygon{renamed}, []int{0}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping, added := matchZones(tt.old, tt.new)
+			if !reflect.DeepEqual(mapping, tt.wantMapping) || !reflect.DeepEqual(added, tt.wantAdded) {
+				t.Errorf("matchZones = %v, %v; want %v, %v", mapping, added, tt.wantMapping, tt.wantAdded)
+			}
+		})
+	}
+}
+
+// graphEdges returns the blocking zones of every edge of a graph by node pair,
+// nil for free edges
+func graphEdges(t *testing.T, graph *PRMGraph) map[[2]int][]int {
+	t.Helper()
+	edges := make(map[[2]int][]int)
+	add := func(i, j int, zones []int) {
+		pair := [2]int{min(i, j), max(i, j)}
+		if existing, ok := edges[pair]; ok && !equalInts(existing, zones) {
+			t.Fatalf("edge %v stored with zones %v and %v", pair, existing, zones)
+		}
+		edges[pair] = zones
+	}
+	for i, node := range graph.Nodes {
+		for _, j := range node.Edges {
+			add(i, j, nil)
+		}
+		for _, blocked := range node.BlockedEdges {
+			add(i, blocked.To, sortedInts(blocked.Zones))
+		}
+	}
+	return edges
+}
+
+// freshEdges returns the edges BuildPRMGraph would create between the nodes
+// of a graph against the zones: horizontal edges within the connection radius
+// in a layer and vertical edges between the layers of a sample
+func freshEdges(graph *PRMGraph, zones *ZoneIndex) map[[2]int][]int {
+	edges := make(map[[2]int][]int)
+	layers := max(len(graph.AltitudeLayers), 1)
+	graph.BuildIndex()
+	for i, node := range graph.Nodes {
+		for _, j := range graph.index.RadiusSearch(node.Point, graph.ConnectionRadius) {
+			if j > i && graph.Nodes[j].Point.Z == node.Point.Z {
+				edges[[2]int{i, j}] = graph.edgeBlockersBetween(i, j, zones)
+			}
+		}
+		if (i+1)%layers != 0 {
+			edges[[2]int{i, i + 1}] = graph.edgeBlockersBetween(i, i+1, zones)
+		}
+	}
+	return edges
+}
+
+func TestRepairPRMGraphMatchesFreshBuild(t *testing.T) {
+	kept := squareZone(4.0, 51.5, 0.6)
+	removed := squareZone(5.0, 52.0, 0.6)
+	banded := squareZone(6.0, 52.5, 0.5)
+	banded.Floor = 50
+	banded.Ceiling = 150
+	restricted := squareZone(4.5, 52.8, 0.5)
+	restricted.Category = ZoneRestricted
+	added := squareZone(5.2, 51.2, 0.7)
+	added.Holes = [][]Point{squareZone(5.4, 51.4, 0.3).Vertices}
+	addedBanded := squareZone(3.6, 52.6, 0.5)
+	addedBanded.Floor = 0
+	addedBanded.Ceiling = 60
+
+	before := []Polygon{kept, restricted, removed, banded}
+	after := []Polygon{added, kept, banded, restricted, addedBanded}
+	layers := []float64{0, 100}
+
+	for _, step := range []struct {
+		name     string
+		old, new []Polygon
+	}{
+		{"add and remove zones", before, after},
+		{"undo", after, before},
+	} {
+		t.Run(step.name, func(t *testing.T) {
+			oldZones := NewZoneIndex(step.old).WithFilter(isHardZone)
+			newZones := NewZoneIndex(step.new).WithFilter(isHardZone)
+			graph := BuildPRMGraph(400, 0.3, oldZones, layers)
+
+			repaired, repair := RepairPRMGraph(graph, oldZones, newZones)
+			if repair.ZonesAdded == 0 || repair.ZonesRemoved == 0 {
+				t.Fatalf("repair found %d added and %d removed zones", repair.ZonesAdded, repair.ZonesRemoved)
+			}
+			if repaired.ZoneSignature != newZones.Signature() {
+				t.Error("repaired graph keeps the old zone signature")
+			}
+
+			for i, node := range repaired.Nodes {
+				if want := newZones.ZonesContainingPoint(node.Point); !equalInts(sortedInts(node.Zones), sortedInts(want)) {
+					t.Fatalf("node %d zones %v, want %v", i, node.Zones, want)
+				}
+			}
+
+			got, want := graphEdges(t, repaired), freshEdges(repaired, newZones)
+			if len(got) != len(want) {
+				t.Errorf("repaired graph has %d edges, a fresh build %d", len(got), len(want))
+			}
+			for pair, zones := range want {
+				if blockers, ok := got[pair]; !ok {
+					t.Fatalf("edge %v missing from the repaired graph", pair)
+				} else if !equalInts(blockers, zones) {
+					t.Fatalf("edge %v blocked by %v, a fresh build by %v", pair, blockers, zones)
+				}
+			}
+
+			// The old graph is left untouched
+			if !reflect.DeepEqual(graphEdges(t, graph), freshEdges(graph, oldZones)) {
+				t.Error("repair changed the old graph")
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// maxValidateRouteBytes limits the size of a route to validate
+const maxValidateRouteBytes = 10 << 20
+
+// ValidateRouteRequest is a polyline planned outside the motion planner
+// The options select the permit, departure time and vehicle the route is
+// checked for; planning options are ignored
+type ValidateRouteRequest struct {
+	Path []Point `json:"path"`
+	RouteOptions
+}
+
+// ZoneCrossing describes how a segment passes through a no-fly zone
+type ZoneCrossing struct {
+	AvoidedZone
+	Temporary     bool    `json:"temporary,omitempty"`
+	Intersections []Point `json:"intersections"`        // Where the segment crosses the zone boundary, in flight order
+	LengthMeters  float64 `json:"lengthMeters"`         // Length of the segment inside the zone
+	BufferOnly    bool    `json:"bufferOnly,omitempty"` // Enters only the safety buffer around the zone
+}
+
+// SegmentValidation lists the zones a segment of the route passes through
+type SegmentValidation struct {
+	Index           int            `json:"index"`
+	From            Point          `json:"from"`
+	To              Point          `json:"to"`
+	DistanceMeters  float64        `json:"distanceMeters"`
+	Violations      []ZoneCrossing `json:"violations,omitempty"`      // Zones that may not be entered
+	RestrictedZones []ZoneCrossing `json:"restrictedZones,omitempty"` // Zones that may be entered with restrictions
+}
+
+// RouteValidation is the result of checking a route against the no-fly zones
+type RouteValidation struct {
+	Success                  bool                `json:"success"`
+	Message                  string              `json:"message,omitempty"`
+	Valid                    bool                `json:"valid"` // No segment enters a zone that may not be entered
+	DistanceMeters           float64             `json:"distanceMeters"`
+	ViolationMeters          float64             `json:"violationMeters"`                    // Length inside zones that may not be entered
+	RestrictedDistanceMeters float64             `json:"restrictedDistanceMeters,omitempty"` // Length inside restricted zones
+	ZonesViolated            []AvoidedZone       `json:"zonesViolated,omitempty"`
+	FlightWindow             *TimeWindow         `json:"flightWindow,omitempty"` // Window the temporary zones are checked for
+	Segments                 []SegmentValidation `json:"segments"`
+}
+
+// ValidateRoute checks every segment of a route against the zones that may not
+// be entered and the restricted zones, including temporary zones active while
+// the route is flown
+func ValidateRoute(path []Point, opts PlanOptions) RouteValidation {
+	validation := RouteValidation{Success: true, Valid: true}
+	for i := 0; i < len(path)-1; i++ {
+		validation.DistanceMeters += path[i].DistanceMeters(path[i+1])
+	}
+	window := &TimeWindow{Start: opts.Departure, End: opts.Departure.Add(opts.Vehicle.EstimateDuration(validation.DistanceMeters))}
+	validation.FlightWindow = window
+
+	hard := opts.hardZoneIndexes(window)
+	restricted := opts.restrictedZoneIndexes(window)
+	violated := make(map[*Polygon]bool)
+	for i := 0; i < len(path)-1; i++ {
+		seg := LineSegment{P1: path[i], P2: path[i+1]}
+		segment := SegmentValidation{Index: i, From: seg.P1, To: seg.P2, DistanceMeters: seg.P1.DistanceMeters(seg.P2)}
+
+		for j, zones := range hard {
+			for _, zone := range zonesOnSegment(seg, zones) {
+				crossing := newZoneCrossing(seg, zones.Zones[zone], j > 0)
+				segment.Violations = append(segment.Violations, crossing)
+				validation.ViolationMeters += crossing.LengthMeters
+				if polygon := &zones.Zones[zone]; !violated[polygon] {
+					violated[polygon] = true
+					validation.ZonesViolated = append(validation.ZonesViolated, crossing.AvoidedZone)
+				}
+			}
+		}
+		for j, zones := range restricted {
+			for _, zone := range zonesOnSegment(seg, zones) {
+				crossing := newZoneCrossing(seg, zones.Zones[zone], j > 0)
+				segment.RestrictedZones = append(segment.RestrictedZones, crossing)
+				validation.RestrictedDistanceMeters += crossing.LengthMeters
+			}
+		}
+
+		validation.Valid = validation.Valid && len(segment.Violations) == 0
+		validation.Segments = append(validation.Segments, segment)
+	}
+	return validation
+}
+
+// zonesOnSegment returns the indices of the zones a segment crosses, enters
+// the safety buffer of, or lies inside while its altitude range overlaps the
+// zone's band, like IsPathClear
+func zonesOnSegment(seg LineSegment, zones *ZoneIndex) []int {
+	result := zones.ZonesIntersectingSegment(seg)
+	low, high := segmentAltitudeRange(seg)
+	for _, p := range []Point{seg.P1, seg.P2} {
+		for _, zone := range zones.ZonesContainingPointBetween(p, low, high) {
+			if !containsInt(result, zone) {
+				result = append(result, zone)
+			}
+		}
+	}
+	return result
+}
+
+// newZoneCrossing returns where a segment crosses a zone boundary and how much
+// of it lies inside the zone
+func newZoneCrossing(seg LineSegment, zone Polygon, temporary bool) ZoneCrossing {
+	crossing := ZoneCrossing{
+		AvoidedZone:   AvoidedZone{GID: zone.GID, LocalType: zone.LocalType, SourceTxt: zone.SourceTxt},
+		Temporary:     temporary,
+		Intersections: []Point{},
+		LengthMeters:  SegmentLengthInsidePolygon(seg, zone),
+	}
+
+	ts := segmentCrossings(seg, zone)
+	sort.Float64s(ts)
+	for _, t := range ts {
+		crossing.Intersections = append(crossing.Intersections, Point{
+			X: seg.P1.X + t*(seg.P2.X-seg.P1.X),
+			Y: seg.P1.Y + t*(seg.P2.Y-seg.P1.Y),
+			Z: seg.P1.Z + t*(seg.P2.Z-seg.P1.Z),
+		})
+	}
+	crossing.BufferOnly = crossing.LengthMeters == 0 && len(ts) == 0
+	return crossing
+}
+
+// parseValidateRouteRequest reads a route to validate from a JSON request with
+// a path, or from a GeoJSON LineString geometry, Feature or FeatureCollection
+// whose first LineString is the route; Feature properties hold the options
+func parseValidateRouteRequest(data []byte) (ValidateRouteRequest, error) {
+	var req ValidateRouteRequest
+	var probe struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return req, err
+	}
+
+	var feature GeoJSONFeature
+	switch probe.Type {
+	case "":
+		err := json.Unmarshal(data, &req)
+		return req, err
+	case "LineString":
+		if err := json.Unmarshal(data, &feature.Geometry); err != nil {
+			return req, err
+		}
+	case "Feature":
+		if err := json.Unmarshal(data, &feature); err != nil {
+			return req, err
+		}
+	case "FeatureCollection":
+		var collection GeoJSONFeatureCollection
+		if err := json.Unmarshal(data, &collection); err != nil {
+			return req, err
+		}
+		for _, f := range collection.Features {
+			if f.Geometry.Type == "LineString" {
+				feature = f
+				break
+			}
+		}
+	default:
+		return req, fmt.Errorf("unsupported GeoJSON type %q", probe.Type)
+	}
+
+	if feature.Geometry.Type != "LineString" {
+		return req, fmt.Errorf("no LineString geometry")
+	}
+	var coords [][]float64
+	if err := json.Unmarshal(feature.Geometry.Coordinates, &coords); err != nil {
+		return req, err
+	}
+	for _, coord := range coords {
+		if len(coord) < 2 {
+			return req, fmt.Errorf("invalid position %v", coord)
+		}
+		p := Point{X: coord[0], Y: coord[1]}
+		if len(coord) > 2 {
+			p.Z = coord[2]
+		}
+		req.Path = append(req.Path, p)
+	}
+
+	if feature.Properties != nil {
+		properties, _ := json.Marshal(feature.Properties)
+		if err := json.Unmarshal(properties, &req.RouteOptions); err != nil {
+			return req, fmt.Errorf("invalid properties: %w", err)
+		}
+	}
+	return req, nil
+}
+
+// POST /validateRoute - Check an externally planned route against the no-fly zones
+func validateRouteHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("========================================")
+	log.Println("🔍 Route validation request received")
+
+	if r.Method != http.MethodPost {
+		log.Printf("❌ Method not allowed: %s\n", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxValidateRouteBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		log.Printf("❌ Request body larger than %d bytes\n", tooLarge.Limit)
+		http.Error(w, fmt.Sprintf("Request body must not exceed %d MB", maxValidateRouteBytes>>20), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Invalid request body: %v\n", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req, err := parseValidateRouteRequest(data)
+	if err != nil {
+		log.Printf("❌ Invalid request body: %v\n", err)
+		http.Error(w, "Body must be JSON with a path or a GeoJSON LineString", http.StatusBadRequest)
+		return
+	}
+	if len(req.Path) < 2 {
+		log.Printf("❌ Path has %d points\n", len(req.Path))
+		http.Error(w, "The path needs at least 2 points", http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("   Path: %d points\n", len(req.Path))
+	if req.Permit != nil {
+		log.Printf("   Permit: %q (%d categories, %d zones)\n", req.Permit.Name, len(req.Permit.Categories), len(req.Permit.GIDs))
+	}
+
+	_, zones := currentPlanningState()
+	validation := ValidateRoute(req.Path, req.PlanOptions(zones))
+	if validation.Valid {
+		log.Printf("✅ Route of %.2f km is clear of no-fly zones\n", validation.DistanceMeters/1000)
+	} else {
+		validation.Message = fmt.Sprintf("Route enters %d no-fly zones for %.0f meters", len(validation.ZonesViolated), validation.ViolationMeters)
+		log.Printf("⚠️  %s\n", validation.Message)
+	}
+	log.Printf("   Flight window %s - %s\n", validation.FlightWindow.Start.Format(time.RFC3339), validation.FlightWindow.End.Format(time.RFC3339))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(validation)
+	log.Println("========================================")
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestValidateRouteVerticalLimits(t *testing.T) {
+	band := squareZone(5.0, 52.0, 0.1)
+	band.Floor = 60
+	band.Ceiling = 70
+	opts := testPlanOptions([]Polygon{band}, nil)
+
+	tests := []struct {
+		name string
+		path []Point
+		want bool
+	}{
+		{"below floor", []Point{{X: 5.02, Y: 52.05, Z: 40}, {X: 5.08, Y: 52.05, Z: 40}}, true},
+		{"above ceiling", []Point{{X: 5.02, Y: 52.05, Z: 100}, {X: 5.08, Y: 52.05, Z: 100}}, true},
+		{"climbing through band", []Point{{X: 5.02, Y: 52.05, Z: 40}, {X: 5.08, Y: 52.05, Z: 100}}, false},
+		{"descending through band", []Point{{X: 5.02, Y: 52.05, Z: 100}, {X: 5.08, Y: 52.05, Z: 40}}, false},
+		{"inside band", []Point{{X: 5.02, Y: 52.05, Z: 65}, {X: 5.08, Y: 52.05, Z: 65}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidateRoute(tt.path, opts); got.Valid != tt.want {
+				t.Errorf("ValidateRoute valid = %v, want %v", got.Valid, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"math"
+	"sort"
+)
+
+// rtreeNodeCapacity is the maximum number of children per R-tree node
+const rtreeNodeCapacity = 16
+
+// RTree is a static R-tree bulk loaded with Sort-Tile-Recursive (STR) packing
+type RTree struct {
+	root *rtreeNode
+}
+
+type rtreeNode struct {
+	box      BoundingBox
+	children []*rtreeNode
+	item     int // Index of the indexed box (leaf entries only)
+}
+
+// Contains checks if a point lies inside the bounding box
+func (b BoundingBox) Contains(p Point) bool {
+	return p.X >= b.MinX && p.X <= b.MaxX && p.Y >= b.MinY && p.Y <= b.MaxY
+}
+
+// Intersects checks if two bounding boxes overlap
+func (b BoundingBox) Intersects(other BoundingBox) bool {
+	return b.MinX <= other.MaxX && b.MaxX >= other.MinX &&
+		b.MinY <= other.MaxY && b.MaxY >= other.MinY
+}
+
+// Union returns the smallest bounding box containing both boxes
+func (b BoundingBox) Union(other BoundingBox) BoundingBox {
+	return BoundingBox{
+		MinX: math.Min(b.MinX, other.MinX),
+		MinY: math.Min(b.MinY, other.MinY),
+		MaxX: math.Max(b.MaxX, other.MaxX),
+		MaxY: math.Max(b.MaxY, other.MaxY),
+	}
+}
+
+// Expand returns the bounding box grown by margin degrees on every side
+func (b BoundingBox) Expand(margin float64) BoundingBox {
+	return BoundingBox{
+		MinX: b.MinX - margin,
+		MinY: b.MinY - margin,
+		MaxX: b.MaxX + margin,
+		MaxY: b.MaxY + margin,
+	}
+}
+
+// center returns the center point of the bounding box
+func (b BoundingBox) center() Point {
+	return Point{X: (b.MinX + b.MaxX) / 2, Y: (b.MinY + b.MaxY) / 2}
+}
+
+// BoundingBoxOfPoints returns the bounding box of a list of points
+func BoundingBoxOfPoints(points []Point) BoundingBox {
+	box := BoundingBox{
+		MinX: math.Inf(1),
+		MinY: math.Inf(1),
+		MaxX: math.Inf(-1),
+		MaxY: math.Inf(-1),
+	}
+	for _, p := range points {
+		box.MinX = math.Min(box.MinX, p.X)
+		box.MinY = math.Min(box.MinY, p.Y)
+		box.MaxX = math.Max(box.MaxX, p.X)
+		box.MaxY = math.Max(box.MaxY, p.Y)
+	}
+	return box
+}
+
+// segmentBoundingBox returns the bounding box of a line segment
+func segmentBoundingBox(seg LineSegment) BoundingBox {
+	return BoundingBox{
+		MinX: math.Min(seg.P1.X, seg.P2.X),
+		MinY: math.Min(seg.P1.Y, seg.P2.Y),
+		MaxX: math.Max(seg.P1.X, seg.P2.X),
+		MaxY: math.Max(seg.P1.Y, seg.P2.Y),
+	}
+}
+
+// NewRTree bulk loads an R-tree over the given boxes
+// The item reported by Search is the index of the box in the input slice
+func NewRTree(boxes []BoundingBox) *RTree {
+	if len(boxes) == 0 {
+		return &RTree{}
+	}
+
+	level := make([]*rtreeNode, len(boxes))
+	for i, box := range boxes {
+		level[i] = &rtreeNode{box: box, item: i}
+	}
+
+	for len(level) > 1 {
+		level = packRTreeLevel(level)
+	}
+
+	return &RTree{root: level[0]}
+}
+
+// packRTreeLevel groups a level of nodes into parent nodes using STR tiling
+func packRTreeLevel(nodes []*rtreeNode) []*rtreeNode {
+	numParents := int(math.Ceil(float64(len(nodes)) / rtreeNodeCapacity))
+	numSlices := int(math.Ceil(math.Sqrt(float64(numParents))))
+	sliceSize := numSlices * rtreeNodeCapacity
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].box.center().X < nodes[j].box.center().X
+	})
+
+	parents := make([]*rtreeNode, 0, numParents)
+	for start := 0; start < len(nodes); start += sliceSize {
+		slice := nodes[start:min(start+sliceSize, len(nodes))]
+		sort.Slice(slice, func(i, j int) bool {
+			return slice[i].box.center().Y < slice[j].box.center().Y
+		})
+
+		for groupStart := 0; groupStart < len(slice); groupStart += rtreeNodeCapacity {
+			group := slice[groupStart:min(groupStart+rtreeNodeCapacity, len(slice))]
+			parent := &rtreeNode{
+				box:      group[0].box,
+				children: append([]*rtreeNode(nil), group...),
+			}
+			for _, child := range group[1:] {
+				parent.box = parent.box.Union(child.box)
+			}
+			parents = append(parents, parent)
+		}
+	}
+
+	return parents
+}
+
+// Search calls fn for every indexed box that intersects the query box
+// Returning false from fn stops the search early
+func (t *RTree) Search(query BoundingBox, fn func(item int) bool) {
+	if t == nil || t.root == nil {
+		return
+	}
+	t.root.search(query, fn)
+}
+
+func (n *rtreeNode) search(query BoundingBox, fn func(item int) bool) bool {
+	if !n.box.Intersects(query) {
+		return true
+	}
+	if n.children == nil {
+		return fn(n.item)
+	}
+	for _, child := range n.children {
+		if !child.search(query, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// zoneEdge is a single polygon ring edge stored in the zone index
+type zoneEdge struct {
+	Zone    int
+	Segment LineSegment
+}
+
+// ZoneIndex is a spatial index over no-fly zone envelopes and edges used by
+// all collision checks
+type ZoneIndex struct {
+	Zones     []Polygon
+	envelopes *RTree
+	edgeTree  *RTree
+	edges     []zoneEdge
+	filter    func(*Polygon) bool // Zones the queries apply to, nil for all zones
+	dynamic   *ZoneIndex          // Zones outside the PRM graph checked alongside, e.g. temporary zones
+	terrain   *TerrainClearance   // Terrain segments must be flyable over, nil to ignore terrain
+}
+
+// WithFilter returns a view of the index whose queries only consider zones
+// accepted by filter; the underlying trees are shared
+func (idx *ZoneIndex) WithFilter(filter func(*Polygon) bool) *ZoneIndex {
+	view := *idx
+	view.filter = filter
+	return &view
+}
+
+// WithDynamicZones returns a view of the index whose collision checks also
+// consider the zones of dynamic, which are not part of the PRM graph
+func (idx *ZoneIndex) WithDynamicZones(dynamic *ZoneIndex) *ZoneIndex {
+	view := *idx
+	view.dynamic = dynamic
+	return &view
+}
+
+// WithTerrain returns a view of the index whose segment checks also reject
+// legs that cannot keep the terrain clearance within the AGL ceiling
+func (idx *ZoneIndex) WithTerrain(terrain *TerrainClearance) *ZoneIndex {
+	view := *idx
+	view.terrain = terrain
+	return &view
+}
+
+// intersectsDynamicZones checks a segment against the dynamic zones only
+func (idx *ZoneIndex) intersectsDynamicZones(seg LineSegment) bool {
+	return idx.dynamic != nil && idx.dynamic.DoesSegmentIntersectZones(seg)
+}
+
+// isClearOfDynamicZones checks a segment against the dynamic zones only,
+// including segments that run inside one of them
+func (idx *ZoneIndex) isClearOfDynamicZones(seg LineSegment) bool {
+	return idx.dynamic == nil || IsPathClear(seg.P1, seg.P2, idx.dynamic)
+}
+
+// applies reports whether a zone is considered by this view of the index
+func (idx *ZoneIndex) applies(zone int) bool {
+	return idx.filter == nil || idx.filter(&idx.Zones[zone])
+}
+
+// appliesBetween reports whether a zone is considered by this view of the index
+// and its vertical limits overlap the altitude range [low, high]
+func (idx *ZoneIndex) appliesBetween(zone int, low, high float64) bool {
+	return idx.Zones[zone].AppliesBetween(low, high) && idx.applies(zone)
+}
+
+// segmentAltitudeRange returns the lowest and highest altitude along a segment
+func segmentAltitudeRange(seg LineSegment) (float64, float64) {
+	return math.Min(seg.P1.Z, seg.P2.Z), math.Max(seg.P1.Z, seg.P2.Z)
+}
+
+// NewZoneIndex builds the R-trees for a set of no-fly zones
+func NewZoneIndex(zones []Polygon) *ZoneIndex {
+	idx := &ZoneIndex{Zones: zones}
+
+	envelopes := make([]BoundingBox, len(zones))
+	var edgeBoxes []BoundingBox
+	for i, zone := range zones {
+		// Grow the boxes by the safety buffer so buffered collisions are found
+		envelope := BoundingBoxOfPoints(zone.Vertices)
+		margin := zoneBufferMargin(zone, envelope)
+
+		envelopes[i] = envelope.Expand(margin)
+		for _, seg := range zone.ringEdges() {
+			idx.edges = append(idx.edges, zoneEdge{Zone: i, Segment: seg})
+			edgeBoxes = append(edgeBoxes, segmentBoundingBox(seg).Expand(margin))
+		}
+	}
+
+	idx.envelopes = NewRTree(envelopes)
+	idx.edgeTree = NewRTree(edgeBoxes)
+	return idx
+}
+
+// zoneBufferMargin returns the safety buffer of a zone in degrees at the
+// latitudes of its envelope
+func zoneBufferMargin(zone Polygon, envelope BoundingBox) float64 {
+	if zone.Buffer <= 0 {
+		return 0
+	}
+	return metersToDegrees(zone.Buffer, math.Max(math.Abs(envelope.MinY), math.Abs(envelope.MaxY)))
+}
+
+// zoneEnvelope returns the bounding box of a zone grown by its safety buffer
+func zoneEnvelope(zone Polygon) BoundingBox {
+	envelope := BoundingBoxOfPoints(zone.Vertices)
+	return envelope.Expand(zoneBufferMargin(zone, envelope))
+}
+
+// Signature returns a hash of the zone geometry and buffers, used to detect
+// when a saved PRM graph was built against different no-fly zones
+func (idx *ZoneIndex) Signature() string {
+	h := sha256.New()
+	for _, zone := range idx.Zones {
+		writeZoneSignature(h, zone)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// zoneSignature returns a hash of a single zone's geometry, buffer, vertical
+// limits and category, used to match zones across reloads
+func zoneSignature(zone Polygon) string {
+	h := sha256.New()
+	writeZoneSignature(h, zone)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeZoneSignature hashes the parts of a zone the PRM graph depends on
+func writeZoneSignature(h hash.Hash, zone Polygon) {
+	var buf [8]byte
+	writeFloat := func(v float64) {
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+		h.Write(buf[:])
+	}
+
+	h.Write([]byte(zone.Category))
+	writeFloat(zone.Buffer)
+	writeFloat(zone.Floor)
+	writeFloat(zone.Ceiling)
+	for _, ring := range zone.rings() {
+		writeFloat(float64(len(ring)))
+		for _, p := range ring {
+			writeFloat(p.X)
+			writeFloat(p.Y)
+		}
+	}
+}
+
+// ZonesContainingPoint returns the indices of all zones whose buffered area contains the point
+func (idx *ZoneIndex) ZonesContainingPoint(p Point) []int {
+	return idx.ZonesContainingPointBetween(p, p.Z, p.Z)
+}
+
+// ZonesContainingPointBetween returns the indices of all zones whose buffered
+// area contains the point horizontally anywhere in the altitude range [low, high]
+func (idx *ZoneIndex) ZonesContainingPointBetween(p Point, low, high float64) []int {
+	var result []int
+	idx.searchEnvelopes(BoundingBox{MinX: p.X, MinY: p.Y, MaxX: p.X, MaxY: p.Y}, low, high, func(zone int) bool {
+		if IsPointInBufferedPolygon(p, idx.Zones[zone]) {
+			result = append(result, zone)
+		}
+		return true
+	})
+	return result
+}
+
+// IsPointBlocked checks if a point lies inside any no-fly zone or its safety buffer
+func (idx *ZoneIndex) IsPointBlocked(p Point) bool {
+	return idx.isPointBlockedBetween(p, p.Z, p.Z)
+}
+
+// isPointBlockedBetween checks if a point lies horizontally inside any no-fly
+// zone or its safety buffer anywhere in the altitude range [low, high]
+func (idx *ZoneIndex) isPointBlockedBetween(p Point, low, high float64) bool {
+	if idx.dynamic != nil && idx.dynamic.isPointBlockedBetween(p, low, high) {
+		return true
+	}
+
+	blocked := false
+	idx.searchEnvelopes(BoundingBox{MinX: p.X, MinY: p.Y, MaxX: p.X, MaxY: p.Y}, low, high, func(zone int) bool {
+		blocked = IsPointInBufferedPolygon(p, idx.Zones[zone])
+		return !blocked
+	})
+	return blocked
+}
+
+// DoesSegmentIntersectZones checks if a line segment crosses the boundary of any
+// no-fly zone or comes closer to it than the zone's safety buffer
+func (idx *ZoneIndex) DoesSegmentIntersectZones(seg LineSegment) bool {
+	if idx.intersectsDynamicZones(seg) || !idx.terrain.IsSegmentFlyable(seg) {
+		return true
+	}
+
+	low, high := segmentAltitudeRange(seg)
+	intersects := false
+	idx.edgeTree.Search(segmentBoundingBox(seg), func(item int) bool {
+		edge := idx.edges[item]
+		if !idx.appliesBetween(edge.Zone, low, high) {
+			return true
+		}
+		if buffer := idx.Zones[edge.Zone].Buffer; buffer > 0 {
+			intersects = segmentDistanceMeters(seg, edge.Segment) < buffer
+		} else {
+			intersects = DoSegmentsIntersect(seg, edge.Segment)
+		}
+		return !intersects
+	})
+	return intersects
+}
+
+// ZonesIntersectingSegment returns the indices of all zones whose boundary the
+// segment crosses or whose safety buffer it enters
+func (idx *ZoneIndex) ZonesIntersectingSegment(seg LineSegment) []int {
+	var result []int
+	seen := make(map[int]bool)
+	add := func(zone int) {
+		if !seen[zone] {
+			seen[zone] = true
+			result = append(result, zone)
+		}
+	}
+
+	low, high := segmentAltitudeRange(seg)
+	idx.edgeTree.Search(segmentBoundingBox(seg), func(item int) bool {
+		edge := idx.edges[item]
+		if seen[edge.Zone] || !idx.appliesBetween(edge.Zone, low, high) {
+			return true
+		}
+		if buffer := idx.Zones[edge.Zone].Buffer; buffer > 0 {
+			if segmentDistanceMeters(seg, edge.Segment) < buffer {
+				add(edge.Zone)
+			}
+		} else if DoSegmentsIntersect(seg, edge.Segment) {
+			add(edge.Zone)
+		}
+		return true
+	})
+	return result
+}
+
+// ZonesNearSegment returns the indices of all zones that contain the segment's
+// start or whose boundary comes within meters of the segment, ignoring buffers
+func (idx *ZoneIndex) ZonesNearSegment(seg LineSegment, meters float64) []int {
+	var result []int
+	seen := make(map[int]bool)
+	low, high := segmentAltitudeRange(seg)
+	margin := metersToDegrees(meters, math.Max(math.Abs(seg.P1.Y), math.Abs(seg.P2.Y)))
+
+	idx.searchEnvelopes(segmentBoundingBox(seg).Expand(margin), low, high, func(zone int) bool {
+		if IsPointInPolygon(seg.P1, idx.Zones[zone]) {
+			seen[zone] = true
+			result = append(result, zone)
+		}
+		return true
+	})
+	idx.edgeTree.Search(segmentBoundingBox(seg).Expand(margin), func(item int) bool {
+		edge := idx.edges[item]
+		if !seen[edge.Zone] && idx.appliesBetween(edge.Zone, low, high) && segmentDistanceMeters(seg, edge.Segment) <= meters {
+			seen[edge.Zone] = true
+			result = append(result, edge.Zone)
+		}
+		return true
+	})
+	return result
+}
+
+// LengthInsideZones returns the length in meters of a line segment that lies
+// inside the zones, summed over all zones it passes through
+func (idx *ZoneIndex) LengthInsideZones(seg LineSegment) float64 {
+	length := 0.0
+	if idx.dynamic != nil {
+		length += idx.dynamic.LengthInsideZones(seg)
+	}
+	low, high := segmentAltitudeRange(seg)
+	idx.searchEnvelopes(segmentBoundingBox(seg), low, high, func(zone int) bool {
+		length += SegmentLengthInsidePolygon(seg, idx.Zones[zone])
+		return true
+	})
+	return length
+}
+
+// searchEnvelopes calls fn for every applicable zone whose envelope intersects
+// the query box and whose vertical limits overlap the altitude range [low, high]
+func (idx *ZoneIndex) searchEnvelopes(query BoundingBox, low, high float64, fn func(zone int) bool) {
+	idx.envelopes.Search(query, func(zone int) bool {
+		if !idx.appliesBetween(zone, low, high) {
+			return true
+		}
+		return fn(zone)
+	})
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// squareZone returns a square hard zone with its lower left corner at (x, y)
+func squareZone(x, y, size float64) Polygon {
+	return Polygon{
+		Vertices: []Point{{X: x, Y: y}, {X: x + size, Y: y}, {X: x + size, Y: y + size}, {X: x, Y: y + size}},
+		Category: ZoneHard,
+	}
+}
+
+// randomZones returns square zones scattered over a small area, some with
+// holes, safety buffers and vertical limits
+func randomZones(rng *rand.Rand, count int) []Polygon {
+	zones := make([]Polygon, count)
+	for i := range zones {
+		size := 0.005 + rng.Float64()*0.02
+		zone := squareZone(5+rng.Float64()*0.2, 52+rng.Float64()*0.2, size)
+		if rng.Intn(3) == 0 {
+			hole := squareZone(zone.Vertices[0].X+size/4, zone.Vertices[0].Y+size/4, size/2)
+			zone.Holes = [][]Point{hole.Vertices}
+		}
+		if rng.Intn(3) == 0 {
+			zone.Buffer = 50 + rng.Float64()*200
+		}
+		if rng.Intn(4) == 0 {
+			zone.Floor = 60
+			zone.Ceiling = 120
+		}
+		zones[i] = zone
+	}
+	return zones
+}
+
+// randomSegment returns a segment of up to 0.05 degrees inside the zone area
+func randomSegment(rng *rand.Rand) LineSegment {
+	p1 := Point{X: 5 + rng.Float64()*0.2, Y: 52 + rng.Float64()*0.2, Z: float64(rng.Intn(4)) * 50}
+	p2 := Point{X: p1.X + (rng.Float64()*2-1)*0.05, Y: p1.Y + (rng.Float64()*2-1)*0.05, Z: float64(rng.Intn(4)) * 50}
+	return LineSegment{P1: p1, P2: p2}
+}
+
+// linearZonesIntersectingSegment is the reference for ZonesIntersectingSegment:
+// every zone is checked without the R-trees
+func linearZonesIntersectingSegment(zones []Polygon, seg LineSegment) []int {
+	low, high := segmentAltitudeRange(seg)
+	var result []int
+	for i, zone := range zones {
+		if zone.AppliesBetween(low, high) && DoesSegmentIntersectBufferedPolygon(seg, zone) {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// linearZonesContainingPoint is the reference for ZonesContainingPoint
+func linearZonesContainingPoint(zones []Polygon, p Point) []int {
+	var result []int
+	for i, zone := range zones {
+		if zone.AppliesBetween(p.Z, p.Z) && IsPointInBufferedPolygon(p, zone) {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+func sortedInts(values []int) []int {
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	return sorted
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestZoneIndexMatchesLinearScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	zones := randomZones(rng, 200)
+	idx := NewZoneIndex(zones)
+
+	for i := 0; i < 5000; i++ {
+		seg := randomSegment(rng)
+
+		want := linearZonesIntersectingSegment(zones, seg)
+		if got := sortedInts(idx.ZonesIntersectingSegment(seg)); !equalInts(got, want) {
+			t.Fatalf("ZonesIntersectingSegment(%v) = %v, linear scan %v", seg, got, want)
+		}
+		if got := idx.DoesSegmentIntersectZones(seg); got != (len(want) > 0) {
+			t.Fatalf("DoesSegmentIntersectZones(%v) = %v, linear scan found %v", seg, got, want)
+		}
+
+		want = linearZonesContainingPoint(zones, seg.P1)
+		if got := sortedInts(idx.ZonesContainingPoint(seg.P1)); !equalInts(got, want) {
+			t.Fatalf("ZonesContainingPoint(%v) = %v, linear scan %v", seg.P1, got, want)
+		}
+		if got := idx.IsPointBlocked(seg.P1); got != (len(want) > 0) {
+			t.Fatalf("IsPointBlocked(%v) = %v, linear scan found %v", seg.P1, got, want)
+		}
+	}
+}
+
+func TestZoneIndexFilter(t *testing.T) {
+	restricted := squareZone(5, 52, 0.1)
+	restricted.Category = ZoneRestricted
+	idx := NewZoneIndex([]Polygon{squareZone(5.2, 52, 0.1), restricted}).WithFilter(isHardZone)
+
+	tests := []struct {
+		name string
+		p    Point
+		want []int
+	}{
+		{"inside hard zone", Point{X: 5.25, Y: 52.05}, []int{0}},
+		{"inside filtered zone", Point{X: 5.05, Y: 52.05}, nil},
+		{"outside both", Point{X: 5.15, Y: 52.05}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := idx.ZonesContainingPoint(tt.p); !equalInts(got, tt.want) {
+				t.Errorf("ZonesContainingPoint = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRTreeSearch(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	boxes := make([]BoundingBox, 1000)
+	for i := range boxes {
+		x, y := rng.Float64(), rng.Float64()
+		boxes[i] = BoundingBox{MinX: x, MinY: y, MaxX: x + rng.Float64()*0.05, MaxY: y + rng.Float64()*0.05}
+	}
+	tree := NewRTree(boxes)
+
+	for i := 0; i < 200; i++ {
+		x, y := rng.Float64(), rng.Float64()
+		query := BoundingBox{MinX: x, MinY: y, MaxX: x + 0.1, MaxY: y + 0.1}
+
+		var want []int
+		for j, box := range boxes {
+			if box.Intersects(query) {
+				want = append(want, j)
+			}
+		}
+		var got []int
+		tree.Search(query, func(item int) bool {
+			got = append(got, item)
+			return true
+		})
+		if got = sortedInts(got); !equalInts(got, want) {
+			t.Fatalf("Search(%v) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+// BenchmarkZoneIndex compares segment checks against the bundled no-fly zones
+// using a linear scan over all zones and the R-tree zone index, and times a
+// PRM build on the index
+func BenchmarkZoneIndex(b *testing.B) {
+	zones, err := loadNoFlyZonesFromFiles(BufferConfig{})
+	if err != nil || len(zones) == 0 {
+		b.Skipf("no bundled no-fly zones: %v", err)
+	}
+	indexed := NewZoneIndex(zones).WithFilter(isHardZone)
+
+	const connectionRadius = 0.11
+	rng := rand.New(rand.NewSource(1))
+	segments := make([]LineSegment, 1000)
+	for i := range segments {
+		p1 := Point{
+			X: NetherlandsMinLon + rng.Float64()*(NetherlandsMaxLon-NetherlandsMinLon),
+			Y: NetherlandsMinLat + rng.Float64()*(NetherlandsMaxLat-NetherlandsMinLat),
+		}
+		p2 := Point{
+			X: p1.X + (rng.Float64()*2-1)*connectionRadius,
+			Y: p1.Y + (rng.Float64()*2-1)*connectionRadius,
+		}
+		segments[i] = LineSegment{P1: p1, P2: p2}
+	}
+
+	b.Run("linear", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			seg := segments[i%len(segments)]
+			for j := range zones {
+				if indexed.applies(j) && DoesSegmentIntersectBufferedPolygon(seg, zones[j]) {
+					break
+				}
+			}
+		}
+	})
+	b.Run("indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			indexed.DoesSegmentIntersectZones(segments[i%len(segments)])
+		}
+	})
+	b.Run("build", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			BuildPRMGraph(500, connectionRadius, indexed, nil)
+		}
+	})
+}
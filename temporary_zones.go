@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TemporaryZoneStore holds time-limited (NOTAM-style) no-fly zones
+// They are not part of the PRM graph and are checked per request instead
+type TemporaryZoneStore struct {
+	mu    sync.RWMutex
+	zones []Polygon
+	index *ZoneIndex
+}
+
+// NewTemporaryZoneStore creates a store with the given zones
+func NewTemporaryZoneStore(zones []Polygon) *TemporaryZoneStore {
+	s := &TemporaryZoneStore{}
+	s.replace(zones)
+	return s
+}
+
+// Index returns the spatial index over the current temporary zones
+func (s *TemporaryZoneStore) Index() *ZoneIndex {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index
+}
+
+// Zones returns a copy of the current temporary zones
+func (s *TemporaryZoneStore) Zones() []Polygon {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Polygon(nil), s.zones...)
+}
+
+// Add stores new temporary zones and drops zones that have expired
+func (s *TemporaryZoneStore) Add(zones []Polygon) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replace(append(activeOrFutureZones(s.zones, time.Now()), zones...))
+}
+
+// RemoveGID removes all temporary zones with the given gid and returns how many were removed
+func (s *TemporaryZoneStore) RemoveGID(gid int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := make([]Polygon, 0, len(s.zones))
+	for _, zone := range s.zones {
+		if zone.GID != gid {
+			kept = append(kept, zone)
+		}
+	}
+	removed := len(s.zones) - len(kept)
+	s.replace(kept)
+	return removed
+}
+
+// replace swaps in a new set of zones and rebuilds the index (caller holds the lock)
+func (s *TemporaryZoneStore) replace(zones []Polygon) {
+	s.zones = zones
+	s.index = NewZoneIndex(zones)
+}
+
+// activeOrFutureZones filters out zones whose validity ended before now
+func activeOrFutureZones(zones []Polygon, now time.Time) []Polygon {
+	kept := make([]Polygon, 0, len(zones))
+	for _, zone := range zones {
+		if zone.ValidTo == nil || !zone.ValidTo.Before(now) {
+			kept = append(kept, zone)
+		}
+	}
+	return kept
+}
+
+// TimeWindow is the period a flight is expected to take
+type TimeWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// maxTemporaryZonesBytes limits the size of posted temporary zones
+const maxTemporaryZonesBytes = 10 << 20
+
+// temporaryZonesMiddleware serves the zone list to every origin and only
+// accepts changes that carry the admin token
+func temporaryZonesMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	public, admin := corsMiddleware(next), adminMiddleware(next)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodOptions {
+			public(w, r)
+			return
+		}
+		admin(w, r)
+	}
+}
+
+// GET/POST/DELETE /temporaryZones - Manage temporary no-fly zones
+func temporaryZonesHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("========================================")
+	log.Println("⏳ Temporary zones request received")
+
+	switch r.Method {
+	case http.MethodGet:
+		zones := globalTemporaryZones.Zones()
+		log.Printf("   Returning %d temporary zones\n", len(zones))
+		log.Println("========================================")
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"zones":   zones,
+		})
+
+	case http.MethodPost:
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxTemporaryZonesBytes))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			log.Printf("❌ Request body larger than %d bytes\n", tooLarge.Limit)
+			http.Error(w, fmt.Sprintf("Request body must not exceed %d MB", maxTemporaryZonesBytes>>20), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			log.Printf("❌ Invalid request body: %v\n", err)
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		zones, err := parseZoneFeatureCollection(data, globalBufferConfig)
+		if err != nil || len(zones) == 0 {
+			log.Printf("❌ Invalid GeoJSON FeatureCollection: %v\n", err)
+			http.Error(w, "Body must be a GeoJSON FeatureCollection with Polygon or MultiPolygon features", http.StatusBadRequest)
+			return
+		}
+
+		globalTemporaryZones.Add(zones)
+		log.Printf("✅ Added %d temporary zones\n", len(zones))
+		log.Println("========================================")
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"added":   len(zones),
+		})
+
+	case http.MethodDelete:
+		gid, err := strconv.Atoi(r.URL.Query().Get("gid"))
+		if err != nil {
+			log.Printf("❌ Invalid gid: %v\n", err)
+			http.Error(w, "Query parameter gid is required", http.StatusBadRequest)
+			return
+		}
+
+		removed := globalTemporaryZones.RemoveGID(gid)
+		log.Printf("✅ Removed %d temporary zones with gid %d\n", removed, gid)
+		log.Println("========================================")
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"removed": removed,
+		})
+
+	default:
+		log.Printf("❌ Method not allowed: %s\n", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsActiveDuring(t *testing.T) {
+	at := func(hour int) *time.Time {
+		moment := time.Date(2026, 6, 1, hour, 0, 0, 0, time.UTC)
+		return &moment
+	}
+	tests := []struct {
+		name       string
+		from, to   *time.Time
+		start, end int
+		want       bool
+	}{
+		{"permanent", nil, nil, 10, 11, true},
+		{"inside window", at(8), at(18), 10, 11, true},
+		{"starts during flight", at(11), at(18), 10, 12, true},
+		{"ends during flight", at(8), at(11), 10, 12, true},
+		{"not yet active", at(13), at(18), 10, 12, false},
+		{"expired", at(6), at(8), 10, 12, false},
+		{"open ended", at(8), nil, 10, 12, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone := Polygon{ValidFrom: tt.from, ValidTo: tt.to}
+			if got := zone.IsActiveDuring(*at(tt.start), *at(tt.end)); got != tt.want {
+				t.Errorf("IsActiveDuring = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemporaryZoneStore(t *testing.T) {
+	expired := time.Now().Add(-time.Hour)
+	old := squareZone(5.0, 52.0, 0.1)
+	old.GID, old.ValidTo = 1, &expired
+	store := NewTemporaryZoneStore([]Polygon{old})
+
+	added := squareZone(5.2, 52.0, 0.1)
+	added.GID = 2
+	store.Add([]Polygon{added, added})
+	if zones := store.Zones(); len(zones) != 2 || zones[0].GID != 2 {
+		t.Fatalf("zones after adding %+v, want the expired zone dropped", zones)
+	}
+	if removed := store.RemoveGID(2); removed != 2 || len(store.Index().Zones) != 0 {
+		t.Errorf("removed %d zones, %d left", removed, len(store.Index().Zones))
+	}
+}
+
+func TestPlanRouteAvoidsZonesActiveDuringFlight(t *testing.T) {
+	opts := testPlanOptions(nil, nil)
+	graph := gridGraph(opts.Zones)
+	stops := []Point{{X: 5.02, Y: 52.09}, {X: 5.28, Y: 52.09}}
+
+	validFrom := opts.Departure.Add(time.Hour)
+	validTo := opts.Departure.Add(2 * time.Hour)
+	zone := squareZone(5.12, 52.06, 0.06)
+	zone.ValidFrom, zone.ValidTo = &validFrom, &validTo
+	opts.TemporaryZones = NewZoneIndex([]Polygon{zone})
+
+	if plan := PlanRoute(graph, stops, opts); !plan.Success || !plan.Direct || plan.ActiveTemporaryZones != 0 {
+		t.Errorf("before the zone is active: success %v, direct %v, %d active zones; want a straight line",
+			plan.Success, plan.Direct, plan.ActiveTemporaryZones)
+	}
+
+	opts.Departure = validFrom.Add(10 * time.Minute)
+	plan := PlanRoute(graph, stops, opts)
+	if !plan.Success || plan.Direct || plan.ActiveTemporaryZones != 1 {
+		t.Fatalf("while the zone is active: success %v, direct %v, %d active zones; want a path around it",
+			plan.Success, plan.Direct, plan.ActiveTemporaryZones)
+	}
+	for i := 0; i < len(plan.Path)-1; i++ {
+		if !IsPathClear(plan.Path[i], plan.Path[i+1], NewZoneIndex([]Polygon{zone})) {
+			t.Fatalf("leg %d enters the active temporary zone", i)
+		}
+	}
+}
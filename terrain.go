@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Terrain is an elevation raster (DEM/AHN) in WGS84 longitude/latitude
+// Elevations are stored row by row from north to south, NaN where there is no data
+type Terrain struct {
+	NCols      int
+	NRows      int
+	MinX       float64 // Longitude of the western edge of the grid
+	MinY       float64 // Latitude of the southern edge of the grid
+	CellSize   float64 // Cell size in degrees
+	Elevations []float64
+}
+
+// LoadTerrain reads an ESRI ASCII grid (.asc) elevation file
+// GeoTIFF rasters can be converted with: gdal_translate -of AAIGrid in.tif out.asc
+func LoadTerrain(filename string) (*Terrain, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	scanner.Split(bufio.ScanWords)
+
+	terrain := &Terrain{}
+	header := make(map[string]float64)
+	var first string
+	for scanner.Scan() {
+		key := strings.ToLower(scanner.Text())
+		if key == "" || (key[0] >= '0' && key[0] <= '9') || key[0] == '-' || key[0] == '.' {
+			first = scanner.Text()
+			break
+		}
+		if !scanner.Scan() {
+			break
+		}
+		value, err := strconv.ParseFloat(scanner.Text(), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid header value for %s: %w", key, err)
+		}
+		header[key] = value
+	}
+
+	terrain.NCols = int(header["ncols"])
+	terrain.NRows = int(header["nrows"])
+	terrain.CellSize = header["cellsize"]
+	if terrain.NCols <= 0 || terrain.NRows <= 0 || terrain.CellSize <= 0 {
+		return nil, fmt.Errorf("missing ncols, nrows or cellsize in header")
+	}
+
+	// Grids are anchored either at the lower-left corner or the lower-left cell center
+	if x, ok := header["xllcenter"]; ok {
+		terrain.MinX = x - terrain.CellSize/2
+	} else {
+		terrain.MinX = header["xllcorner"]
+	}
+	if y, ok := header["yllcenter"]; ok {
+		terrain.MinY = y - terrain.CellSize/2
+	} else {
+		terrain.MinY = header["yllcorner"]
+	}
+
+	noData, hasNoData := header["nodata_value"]
+	terrain.Elevations = make([]float64, 0, terrain.NCols*terrain.NRows)
+	parse := func(text string) error {
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return fmt.Errorf("invalid elevation %q: %w", text, err)
+		}
+		if hasNoData && value == noData {
+			value = math.NaN()
+		}
+		terrain.Elevations = append(terrain.Elevations, value)
+		return nil
+	}
+
+	if first != "" {
+		if err := parse(first); err != nil {
+			return nil, err
+		}
+	}
+	for scanner.Scan() {
+		if err := parse(scanner.Text()); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if len(terrain.Elevations) != terrain.NCols*terrain.NRows {
+		return nil, fmt.Errorf("expected %d elevations, found %d", terrain.NCols*terrain.NRows, len(terrain.Elevations))
+	}
+
+	return terrain, nil
+}
+
+// cell returns the elevation of a grid cell, or NaN outside the grid
+func (t *Terrain) cell(col, row int) float64 {
+	if col < 0 || row < 0 || col >= t.NCols || row >= t.NRows {
+		return math.NaN()
+	}
+	return t.Elevations[row*t.NCols+col]
+}
+
+// ElevationAt returns the terrain elevation in meters at a point, bilinearly
+// interpolated between cell centers, and false if there is no data
+func (t *Terrain) ElevationAt(p Point) (float64, bool) {
+	// Fractional column and row measured from the center of the north-west cell
+	fx := (p.X-t.MinX)/t.CellSize - 0.5
+	fy := (t.MinY+float64(t.NRows)*t.CellSize-p.Y)/t.CellSize - 0.5
+	col, row := int(math.Floor(fx)), int(math.Floor(fy))
+	tx, ty := fx-float64(col), fy-float64(row)
+
+	elevation, weight := 0.0, 0.0
+	for _, c := range [4]struct {
+		col, row int
+		w        float64
+	}{
+		{col, row, (1 - tx) * (1 - ty)},
+		{col + 1, row, tx * (1 - ty)},
+		{col, row + 1, (1 - tx) * ty},
+		{col + 1, row + 1, tx * ty},
+	} {
+		if value := t.cell(c.col, c.row); !math.IsNaN(value) && c.w > 0 {
+			elevation += value * c.w
+			weight += c.w
+		}
+	}
+
+	// Near the edge of the grid or of missing data, use the cells that have data
+	if weight == 0 {
+		if value := t.cell(int(math.Round(fx)), int(math.Round(fy))); !math.IsNaN(value) {
+			return value, true
+		}
+		return 0, false
+	}
+	return elevation / weight, true
+}
+
+// groundAt returns the elevation at a point, treating missing data as sea level
+func (t *Terrain) groundAt(p Point) float64 {
+	elevation, _ := t.ElevationAt(p)
+	return elevation
+}
+
+// SegmentElevationRange samples the terrain every half cell along a segment
+// and returns the lowest and highest elevation found
+func (t *Terrain) SegmentElevationRange(seg LineSegment) (float64, float64) {
+	length := math.Hypot(seg.P2.X-seg.P1.X, seg.P2.Y-seg.P1.Y)
+	steps := int(math.Ceil(length/(t.CellSize/2))) + 1
+
+	low, high := math.Inf(1), math.Inf(-1)
+	for i := 0; i <= steps; i++ {
+		s := float64(i) / float64(steps)
+		elevation := t.groundAt(Point{
+			X: seg.P1.X + s*(seg.P2.X-seg.P1.X),
+			Y: seg.P1.Y + s*(seg.P2.Y-seg.P1.Y),
+		})
+		low = math.Min(low, elevation)
+		high = math.Max(high, elevation)
+	}
+	return low, high
+}
+
+// TerrainClearance holds the terrain and the height limits a flight must keep above it
+// Legs are flown level, so a leg is only flyable if the terrain along it varies
+// less than the band between the minimum clearance and the AGL ceiling
+type TerrainClearance struct {
+	Terrain      *Terrain
+	MinClearance float64 // Minimum height above terrain and obstacles in meters
+	MaxAGL       float64 // Maximum height above ground level in meters
+}
+
+// IsSegmentFlyable reports whether a level leg along the segment can keep the
+// minimum clearance everywhere without exceeding the AGL ceiling anywhere
+func (c *TerrainClearance) IsSegmentFlyable(seg LineSegment) bool {
+	if c == nil {
+		return true
+	}
+	low, high := c.Terrain.SegmentElevationRange(seg)
+	return high+c.MinClearance <= low+c.MaxAGL
+}
+
+// Signature returns a hash of the terrain and height limits, used to detect
+// when a saved PRM graph was built against a different terrain
+func (c *TerrainClearance) Signature() string {
+	if c == nil {
+		return ""
+	}
+
+	h := sha256.New()
+	var buf [8]byte
+	writeFloat := func(v float64) {
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+		h.Write(buf[:])
+	}
+
+	t := c.Terrain
+	for _, v := range []float64{c.MinClearance, c.MaxAGL, float64(t.NCols), float64(t.NRows), t.MinX, t.MinY, t.CellSize} {
+		writeFloat(v)
+	}
+	for _, v := range t.Elevations {
+		writeFloat(v)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ProfilePoint is the terrain and flight altitude at a waypoint
+type ProfilePoint struct {
+	DistanceMeters float64  `json:"distanceMeters"`      // Distance along the path
+	GroundMeters   float64  `json:"groundMeters"`        // Terrain elevation
+	AltitudeMeters float64  `json:"altitudeMeters"`      // Flight altitude above sea level
+	AGLMeters      float64  `json:"aglMeters"`           // Flight altitude above the terrain
+	LegMinAGL      *float64 `json:"legMinAGL,omitempty"` // Lowest height above terrain on the leg departing this waypoint, nil at the last one
+	LegMaxAGL      *float64 `json:"legMaxAGL,omitempty"` // Highest height above ground on the leg departing this waypoint, nil at the last one
+}
+
+// AltitudeProfile returns the flight altitude at every waypoint of a path
+// Each leg is flown level at the waypoint's planned height above ground (Z),
+// raised to keep the minimum clearance over the highest terrain on the leg and
+// capped by the AGL ceiling over the lowest; altitude changes happen at the
+// waypoints, and the last waypoint keeps the altitude of the final leg
+// It fails when a leg cannot keep the minimum clearance below the AGL ceiling
+func (c *TerrainClearance) AltitudeProfile(path []Point) ([]ProfilePoint, error) {
+	if c == nil || len(path) == 0 {
+		return nil, nil
+	}
+
+	profile := make([]ProfilePoint, len(path))
+	distance := 0.0
+	for i, p := range path {
+		ground := c.Terrain.groundAt(p)
+		point := ProfilePoint{DistanceMeters: distance, GroundMeters: ground}
+
+		if i < len(path)-1 {
+			low, high := c.Terrain.SegmentElevationRange(LineSegment{P1: p, P2: path[i+1]})
+			if high+c.MinClearance > low+c.MaxAGL {
+				return nil, fmt.Errorf("leg %d cannot keep %.0f m above the terrain without exceeding %.0f m above ground",
+					i+1, c.MinClearance, c.MaxAGL)
+			}
+			altitude := math.Max(ground+p.Z, high+c.MinClearance)
+			altitude = math.Min(altitude, low+c.MaxAGL)
+			point.AltitudeMeters = altitude
+			legMin, legMax := altitude-high, altitude-low
+			point.LegMinAGL, point.LegMaxAGL = &legMin, &legMax
+			distance += p.DistanceMeters(path[i+1])
+		} else if i > 0 {
+			point.AltitudeMeters = profile[i-1].AltitudeMeters
+		} else {
+			point.AltitudeMeters = ground + math.Max(p.Z, c.MinClearance)
+		}
+
+		point.AGLMeters = point.AltitudeMeters - ground
+		profile[i] = point
+	}
+
+	return profile, nil
+}
+
+// firstBlockedLeg returns the index of the first leg of a path whose flown
+// heights above ground from the altitude profile enter a zone, counting the
+// climb or descent at the waypoint the leg departs from, or -1 if none does
+// Zone limits and the planned Z are heights above ground, so the profile may
+// take a leg into a zone's vertical limits the planned path stayed out of
+func firstBlockedLeg(path []Point, profile []ProfilePoint, zones *ZoneIndex) int {
+	if profile == nil {
+		return -1
+	}
+	for i := 0; i+1 < len(path); i++ {
+		from, to := path[i], path[i+1]
+		from.Z, to.Z = *profile[i].LegMinAGL, *profile[i].LegMaxAGL
+		if !IsPathClear(from, to, zones) {
+			return i
+		}
+		if i > 0 {
+			arriving := profile[i-1].AltitudeMeters - profile[i].GroundMeters
+			low, high := math.Min(arriving, profile[i].AGLMeters), math.Max(arriving, profile[i].AGLMeters)
+			if zones.isPointBlockedBetween(path[i], low, high) {
+				return i
+			}
+		}
+	}
+	return -1
+}
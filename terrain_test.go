@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+// stepTerrain returns a 10x10 grid of 0.01 degree cells from (5, 52) whose
+// western half lies at sea level and eastern half at the given elevation
+func stepTerrain(elevation float64) *Terrain {
+	terrain := &Terrain{NCols: 10, NRows: 10, MinX: 5, MinY: 52, CellSize: 0.01}
+	for row := 0; row < terrain.NRows; row++ {
+		for col := 0; col < terrain.NCols; col++ {
+			value := 0.0
+			if col >= 5 {
+				value = elevation
+			}
+			terrain.Elevations = append(terrain.Elevations, value)
+		}
+	}
+	return terrain
+}
+
+func TestAltitudeProfile(t *testing.T) {
+	tests := []struct {
+		name      string
+		elevation float64
+		z         float64
+		wantErr   bool
+		wantMin   float64
+		wantMax   float64
+	}{
+		{"flat", 0, 40, false, 40, 40},
+		{"raised for clearance", 50, 40, false, 30, 80},
+		{"capped by ceiling", 0, 150, false, 120, 120},
+		{"clearance above ceiling", 100, 40, true, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := []Point{{X: 5.01, Y: 52.05, Z: tt.z}, {X: 5.09, Y: 52.05, Z: tt.z}}
+			clearance := &TerrainClearance{Terrain: stepTerrain(tt.elevation), MinClearance: 30, MaxAGL: 120}
+			profile, err := clearance.AltitudeProfile(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AltitudeProfile error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if math.Abs(*profile[0].LegMinAGL-tt.wantMin) > 1e-6 || math.Abs(*profile[0].LegMaxAGL-tt.wantMax) > 1e-6 {
+				t.Errorf("leg AGL %v-%v, want %v-%v", *profile[0].LegMinAGL, *profile[0].LegMaxAGL, tt.wantMin, tt.wantMax)
+			}
+			if profile[1].LegMinAGL != nil || profile[1].LegMaxAGL != nil {
+				t.Error("last waypoint has leg heights")
+			}
+		})
+	}
+}
+
+func TestFirstBlockedLeg(t *testing.T) {
+	band := squareZone(5.0, 52.0, 0.1)
+	band.Floor = 60
+	band.Ceiling = 70
+	zones := NewZoneIndex([]Polygon{band})
+	clearance := &TerrainClearance{Terrain: stepTerrain(50), MinClearance: 30, MaxAGL: 120}
+
+	// Planned below the zone's floor, but raised into it over the high ground
+	path := []Point{{X: 5.01, Y: 52.05, Z: 40}, {X: 5.09, Y: 52.05, Z: 40}}
+	if !IsPathClear(path[0], path[1], zones) {
+		t.Fatal("planned path is not clear")
+	}
+	profile, err := clearance.AltitudeProfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leg := firstBlockedLeg(path, profile, zones); leg != 0 {
+		t.Errorf("firstBlockedLeg = %d, want 0", leg)
+	}
+
+	// Over flat ground the profile keeps the planned height
+	flat := &TerrainClearance{Terrain: stepTerrain(0), MinClearance: 30, MaxAGL: 120}
+	if profile, err = flat.AltitudeProfile(path); err != nil {
+		t.Fatal(err)
+	}
+	if leg := firstBlockedLeg(path, profile, zones); leg != -1 {
+		t.Errorf("firstBlockedLeg over flat ground = %d, want -1", leg)
+	}
+}
+
+func TestAltitudeProfileKeepsZeroLegHeights(t *testing.T) {
+	path := []Point{{X: 5.01, Y: 52.05}, {X: 5.09, Y: 52.05}}
+	clearance := &TerrainClearance{Terrain: stepTerrain(0), MaxAGL: 120}
+	profile, err := clearance.AltitudeProfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(profile[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"legMinAGL":0,`) || !strings.Contains(string(data), `"legMaxAGL":0}`) {
+		t.Errorf("profile point %s drops the zero leg heights", data)
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+)
+
+// maxTourStops limits the stops per tour request, as every pair of stops is planned,
+// and the via points per route request, as every leg is planned
+const maxTourStops = 50
+
+// TourRequest asks for the order in which to visit stops from a depot
+type TourRequest struct {
+	Depot          Point   `json:"depot"`
+	Stops          []Point `json:"stops"`                    // Stops to visit, in any order
+	ReturnToDepot  bool    `json:"returnToDepot,omitempty"`  // Every tour ends back at the depot
+	MaxRangeMeters float64 `json:"maxRangeMeters,omitempty"` // Longest tour in still-air meters, defaults to the vehicle's range
+	RouteOptions
+}
+
+// Tour is one flight from the depot through some of the stops
+type Tour struct {
+	Stops          []int          `json:"stops"` // Indexes of the requested stops, in visiting order
+	Path           []Point        `json:"path"`
+	DistanceMeters float64        `json:"distanceMeters"`
+	Legs           []RouteLeg     `json:"legs"`
+	StopIndexes    []int          `json:"stopIndexes"` // Index in the path of the depot and every stop
+	Trajectory     []Point        `json:"trajectory,omitempty"`
+	Timing         *FlightTiming  `json:"timing,omitempty"`
+	Energy         *EnergySummary `json:"energy,omitempty"`
+}
+
+type TourResponse struct {
+	Success        bool    `json:"success"`
+	Message        string  `json:"message,omitempty"`
+	DistanceMeters float64 `json:"distanceMeters,omitempty"` // Summed over all tours
+	Tours          []Tour  `json:"tours,omitempty"`
+}
+
+// distanceMatrix plans a path between every pair of points and returns their
+// still-air lengths, +Inf where no path exists
+// Without wind the matrix is symmetric and every pair is planned once; random
+// shortcutting is skipped, as only the final tours are returned
+func distanceMatrix(graph *PRMGraph, points []Point, opts PlanOptions) [][]float64 {
+	opts.Kinematics = nil
+	opts.SmoothIterations = 0
+	matrix := make([][]float64, len(points))
+	for i := range matrix {
+		matrix[i] = make([]float64, len(points))
+	}
+
+	for i := range points {
+		for j := range points {
+			if i == j || (opts.Wind == nil && j < i) {
+				continue
+			}
+			leg := planPathInFlightWindow(graph, points[i], points[j], opts)
+			matrix[i][j] = math.Inf(1)
+			if leg.Success {
+				matrix[i][j] = opts.Wind.PathAirMeters(leg.Path, opts.Vehicle.CruiseSpeedMps)
+			}
+			if opts.Wind == nil {
+				matrix[j][i] = matrix[i][j]
+			}
+		}
+	}
+	return matrix
+}
+
+// tourLength returns the length of a tour that starts at the depot (index 0 of
+// the matrix) and visits the stops in order, optionally returning to the depot
+func tourLength(matrix [][]float64, tour []int, returnToDepot bool) float64 {
+	if len(tour) == 0 {
+		return 0
+	}
+	length := matrix[0][tour[0]]
+	for i := 0; i < len(tour)-1; i++ {
+		length += matrix[tour[i]][tour[i+1]]
+	}
+	if returnToDepot {
+		length += matrix[tour[len(tour)-1]][0]
+	}
+	return length
+}
+
+// nearestNeighbourTour visits every stop of the matrix from the depot, always
+// flying to the closest reachable stop not visited yet
+// Stops that cannot be reached from any visited stop are appended last
+func nearestNeighbourTour(matrix [][]float64) []int {
+	visited := make([]bool, len(matrix))
+	tour := make([]int, 0, len(matrix)-1)
+	current := 0
+	for len(tour) < len(matrix)-1 {
+		next := -1
+		for stop := 1; stop < len(matrix); stop++ {
+			if !visited[stop] && (next == -1 || matrix[current][stop] < matrix[current][next]) {
+				next = stop
+			}
+		}
+		visited[next] = true
+		tour = append(tour, next)
+		current = next
+	}
+	return tour
+}
+
+// twoOpt improves a tour by reversing sections of it until no reversal
+// shortens it; lengths are recomputed in full so asymmetric matrices work
+func twoOpt(matrix [][]float64, tour []int, returnToDepot bool) []int {
+	best := tourLength(matrix, tour, returnToDepot)
+	candidate := make([]int, len(tour))
+	for improved := true; improved; {
+		improved = false
+		for i := 0; i < len(tour)-1; i++ {
+			for j := i + 1; j < len(tour); j++ {
+				copy(candidate, tour)
+				for a, b := i, j; a < b; a, b = a+1, b-1 {
+					candidate[a], candidate[b] = candidate[b], candidate[a]
+				}
+				if length := tourLength(matrix, candidate, returnToDepot); length < best-1e-6 {
+					copy(tour, candidate)
+					best = length
+					improved = true
+				}
+			}
+		}
+	}
+	return tour
+}
+
+// splitTour splits a tour through all stops into consecutive tours that each
+// fit within the range, using the fewest tours and then the shortest total length
+func splitTour(matrix [][]float64, tour []int, returnToDepot bool, maxRange float64) ([][]int, error) {
+	type split struct {
+		tours  int
+		length float64
+		prev   int
+	}
+
+	// best[j] is the best split of the first j stops of the tour
+	best := make([]split, len(tour)+1)
+	for j := 1; j <= len(tour); j++ {
+		best[j] = split{tours: math.MaxInt, length: math.Inf(1), prev: -1}
+	}
+	for i := 0; i < len(tour); i++ {
+		if best[i].prev == -1 && i > 0 {
+			continue
+		}
+		for j := i + 1; j <= len(tour); j++ {
+			length := tourLength(matrix, tour[i:j], returnToDepot)
+			if length > maxRange {
+				continue
+			}
+			candidate := split{tours: best[i].tours + 1, length: best[i].length + length, prev: i}
+			if candidate.tours < best[j].tours || (candidate.tours == best[j].tours && candidate.length < best[j].length) {
+				best[j] = candidate
+			}
+		}
+	}
+
+	if best[len(tour)].prev == -1 {
+		for _, stop := range tour {
+			if tourLength(matrix, []int{stop}, returnToDepot) > maxRange {
+				return nil, fmt.Errorf("stop %d cannot be reached within a range of %.1f km", stop-1, maxRange/1000)
+			}
+		}
+		return nil, fmt.Errorf("stops cannot be split into tours within a range of %.1f km", maxRange/1000)
+	}
+
+	var tours [][]int
+	for j := len(tour); j > 0; j = best[j].prev {
+		tours = append([][]int{tour[best[j].prev:j]}, tours...)
+	}
+	return tours, nil
+}
+
+// PlanTours finds the order in which to visit the stops from the depot,
+// split into tours that fit within the range, and plans the path of every tour
+// The order is a nearest neighbour tour over the planned distance matrix,
+// improved with 2-opt before and after it is split; tours whose planned path
+// does not fit within the range are split again
+func PlanTours(graph *PRMGraph, depot Point, stops []Point, returnToDepot bool, maxRange float64, opts PlanOptions) ([]Tour, error) {
+	points := append([]Point{depot}, stops...)
+
+	log.Printf("🔍 Planning the %dx%d distance matrix...\n", len(points), len(points))
+	startTime := time.Now()
+	matrix := distanceMatrix(graph, points, opts)
+	log.Printf("   Distance matrix planned in %v\n", time.Since(startTime))
+
+	for stop := 1; stop < len(points); stop++ {
+		if math.IsInf(matrix[0][stop], 1) || (returnToDepot && math.IsInf(matrix[stop][0], 1)) {
+			return nil, fmt.Errorf("no path between the depot and stop %d", stop-1)
+		}
+	}
+
+	order := twoOpt(matrix, nearestNeighbourTour(matrix), returnToDepot)
+	split, err := splitTour(matrix, order, returnToDepot, maxRange)
+	if err != nil {
+		return nil, err
+	}
+
+	// Battery limits are enforced by the split, so tours never divert to charging sites
+	opts.ChargingSites = nil
+	tours := make([]Tour, 0, len(split))
+	for len(split) > 0 {
+		section := twoOpt(matrix, split[0], returnToDepot)
+		split = split[1:]
+
+		route := []Point{depot}
+		tour := Tour{}
+		for _, stop := range section {
+			route = append(route, points[stop])
+			tour.Stops = append(tour.Stops, stop-1)
+		}
+		if returnToDepot {
+			route = append(route, depot)
+		}
+
+		log.Printf("🧭 Tour %d/%d through %d stops (%.2f km in the matrix)\n",
+			len(tours)+1, len(tours)+len(split)+1, len(section), tourLength(matrix, section, returnToDepot)/1000)
+		plan := PlanRoute(graph, route, opts)
+
+		// The matrix holds A* paths without turns at the request's departure,
+		// so the planned tour can be longer; split it in two when it no longer fits
+		if !plan.Success || plan.AirMeters > maxRange {
+			if len(section) == 1 {
+				if plan.Success {
+					return nil, fmt.Errorf("stop %d cannot be reached within a range of %.1f km", section[0]-1, maxRange/1000)
+				}
+				return nil, fmt.Errorf("tour to stop %d: %s", section[0]-1, plan.Message)
+			}
+			log.Printf("   ⚠️  Planned tour does not fit within %.1f km, splitting it\n", maxRange/1000)
+			half := len(section) / 2
+			split = append([][]int{section[:half], section[half:]}, split...)
+			continue
+		}
+
+		tour.Path = plan.Path
+		tour.DistanceMeters = plan.DistanceMeters
+		tour.Legs = plan.Legs
+		tour.StopIndexes = plan.StopIndexes
+		tour.Trajectory = plan.Trajectory
+		tour.Timing = plan.Timing
+		tour.Energy = plan.Energy
+		tours = append(tours, tour)
+	}
+	return tours, nil
+}
+
+// POST /tour - Optimize the order of stops visited from a depot
+func tourHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("========================================")
+	log.Println("🗺️  Tour request received")
+
+	if r.Method != http.MethodPost {
+		log.Printf("❌ Method not allowed: %s\n", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req TourRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("❌ Invalid request body: %v\n", err)
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.Stops) == 0 || len(req.Stops) > maxTourStops {
+		log.Printf("❌ Invalid number of stops: %d\n", len(req.Stops))
+		http.Error(w, fmt.Sprintf("Between 1 and %d stops are required", maxTourStops), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("   Depot: (%.6f, %.6f, %.0f m)\n", req.Depot.X, req.Depot.Y, req.Depot.Z)
+	log.Printf("   Stops: %d, return to depot: %v\n", len(req.Stops), req.ReturnToDepot)
+
+	prmGraph, zones := currentPlanningState()
+
+	if prmGraph == nil {
+		log.Println("❌ PRM graph not built")
+		http.Error(w, "PRM graph not built. Call /buildPRMGraph first", http.StatusBadRequest)
+		log.Println("========================================")
+		return
+	}
+
+	opts := req.PlanOptions(zones)
+	maxRange := opts.Vehicle.RangeMeters()
+	if req.MaxRangeMeters > 0 {
+		maxRange = math.Min(maxRange, req.MaxRangeMeters)
+	}
+
+	var response TourResponse
+	tours, err := PlanTours(prmGraph, req.Depot, req.Stops, req.ReturnToDepot, maxRange, opts)
+	if err != nil {
+		log.Printf("❌ %v\n", err)
+		response.Message = fmt.Sprintf("Could not plan tours: %v", err)
+	} else {
+		response.Success = true
+		response.Tours = tours
+		for _, tour := range tours {
+			response.DistanceMeters += tour.DistanceMeters
+		}
+		log.Printf("✅ %d tours, %.2f km in total\n", len(tours), response.DistanceMeters/1000)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+	log.Println("========================================")
+}
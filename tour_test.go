@@ -0,0 +1,112 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// lineMatrix returns the distance matrix of points along a line, with the
+// depot at index 0
+func lineMatrix(positions ...float64) [][]float64 {
+	matrix := make([][]float64, len(positions))
+	for i := range matrix {
+		matrix[i] = make([]float64, len(positions))
+		for j := range matrix[i] {
+			matrix[i][j] = math.Abs(positions[i] - positions[j])
+		}
+	}
+	return matrix
+}
+
+// circleMatrix returns the distance matrix of a depot at the center of a
+// circle of radius 10 and four stops on it, 5 apart in visiting order
+func circleMatrix() [][]float64 {
+	step := 2 * math.Asin(0.25)
+	points := []Point{{}}
+	for i := 0; i < 4; i++ {
+		points = append(points, Point{X: 10 * math.Cos(float64(i)*step), Y: 10 * math.Sin(float64(i)*step)})
+	}
+	matrix := make([][]float64, len(points))
+	for i := range matrix {
+		matrix[i] = make([]float64, len(points))
+		for j := range matrix[i] {
+			matrix[i][j] = distance(points[i], points[j])
+		}
+	}
+	return matrix
+}
+
+func TestSplitTour(t *testing.T) {
+	matrix := circleMatrix()
+	blocked := circleMatrix()
+	blocked[1][2], blocked[2][1] = math.Inf(1), math.Inf(1)
+	unreachable := circleMatrix()
+	unreachable[0][2], unreachable[1][2] = math.Inf(1), math.Inf(1)
+
+	tests := []struct {
+		name          string
+		matrix        [][]float64
+		returnToDepot bool
+		maxRange      float64
+		want          [][]int
+		wantErr       bool
+	}{
+		{"single tour", matrix, false, 100, [][]int{{1, 2, 3, 4}}, false},
+		{"single tour returning", matrix, true, 35.01, [][]int{{1, 2, 3, 4}}, false},
+		{"two tours returning", matrix, true, 25.01, [][]int{{1, 2}, {3, 4}}, false},
+		{"two tours", matrix, false, 15.01, [][]int{{1, 2}, {3, 4}}, false},
+		{"every stop alone", matrix, true, 20.01, [][]int{{1}, {2}, {3}, {4}}, false},
+		{"stops out of range", matrix, true, 19, nil, true},
+		{"blocked hop", blocked, false, 100, [][]int{{1}, {2, 3, 4}}, false},
+		{"unreachable stop", unreachable, false, 100, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitTour(tt.matrix, []int{1, 2, 3, 4}, tt.returnToDepot, tt.maxRange)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitTour error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitTour = %v, want %v", got, tt.want)
+			}
+			for _, tour := range got {
+				if length := tourLength(tt.matrix, tour, tt.returnToDepot); length > tt.maxRange {
+					t.Errorf("tour %v of %v exceeds the range of %v", tour, length, tt.maxRange)
+				}
+			}
+		})
+	}
+}
+
+func TestTwoOpt(t *testing.T) {
+	matrix := lineMatrix(0, 10, 20, 30, 40)
+	if got := twoOpt(matrix, []int{3, 1, 4, 2}, false); !reflect.DeepEqual(got, []int{1, 2, 3, 4}) {
+		t.Errorf("twoOpt = %v, want [1 2 3 4]", got)
+	}
+	if got := nearestNeighbourTour(lineMatrix(0, 30, -10, 20)); !reflect.DeepEqual(got, []int{2, 3, 1}) {
+		t.Errorf("nearestNeighbourTour = %v, want [2 3 1]", got)
+	}
+}
+
+func TestPlanToursSplitsToursLongerThanPlanned(t *testing.T) {
+	opts := testPlanOptions(nil, nil)
+	opts.Kinematics = &Kinematics{TurnRadius: 300}
+	depot := Point{X: 5.0, Y: 52.0}
+	stops := []Point{{X: 5.0, Y: 52.05}, {X: 5.08, Y: 52.05}}
+
+	// The matrix has straight lines, so the tour fits until turns are added
+	straight := depot.DistanceMeters(stops[0]) + stops[0].DistanceMeters(stops[1])
+	tours, err := PlanTours(nil, depot, stops, false, straight+1, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tours) != 2 {
+		t.Fatalf("%d tours, want 2", len(tours))
+	}
+	for _, tour := range tours {
+		if tour.Trajectory == nil || PathLengthMeters(tour.Trajectory) > straight+1 {
+			t.Errorf("tour through %v flies %.0f m, range %.0f m", tour.Stops, PathLengthMeters(tour.Trajectory), straight+1)
+		}
+	}
+}
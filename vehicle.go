@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// VehicleProfile describes the performance of a drone used to estimate flight times
+type VehicleProfile struct {
+	Name           string  `json:"name,omitempty"`
+	CruiseSpeedMps float64 `json:"cruiseSpeedMps,omitempty"` // Horizontal speed in meters per second
+	ClimbRateMps   float64 `json:"climbRateMps,omitempty"`   // Vertical speed when climbing
+	DescentRateMps float64 `json:"descentRateMps,omitempty"` // Vertical speed when descending
+	TakeoffSeconds float64 `json:"takeoffSeconds,omitempty"` // Time from departure until the first waypoint
+	LandingSeconds float64 `json:"landingSeconds,omitempty"` // Time from the last waypoint until landed
+
+	BatteryWh          float64 `json:"batteryWh,omitempty"`          // Battery capacity, 0 for unlimited range
+	ConsumptionWhPerKm float64 `json:"consumptionWhPerKm,omitempty"` // Energy used per kilometer flown
+	ReservePercent     float64 `json:"reservePercent,omitempty"`     // Part of the battery that may not be used
+	ChargingSeconds    float64 `json:"chargingSeconds,omitempty"`    // Time spent recharging at a charging stop
+
+	given map[string]bool // JSON keys present when decoded, nil for profiles built in code
+}
+
+// DefaultVehicleProfile is used when no vehicle profile file is loaded
+var DefaultVehicleProfile = VehicleProfile{
+	Name:           "default",
+	CruiseSpeedMps: 15,
+	ClimbRateMps:   3,
+	DescentRateMps: 2,
+	TakeoffSeconds: 30,
+	LandingSeconds: 30,
+
+	ConsumptionWhPerKm: 15,
+	ReservePercent:     20,
+	ChargingSeconds:    1800,
+}
+
+// LoadVehicleProfile reads a vehicle profile from a JSON file, taking missing
+// values from the default profile
+func LoadVehicleProfile(filename string) (VehicleProfile, error) {
+	var profile VehicleProfile
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return DefaultVehicleProfile, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return DefaultVehicleProfile, fmt.Errorf("failed to parse vehicle profile: %w", err)
+	}
+
+	return profile.WithDefaults(DefaultVehicleProfile), nil
+}
+
+// UnmarshalJSON decodes a profile and records which values were given, so
+// WithDefaults keeps values explicitly set to 0
+func (v *VehicleProfile) UnmarshalJSON(data []byte) error {
+	type plain VehicleProfile
+	if err := json.Unmarshal(data, (*plain)(v)); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	v.given = make(map[string]bool, len(fields))
+	for key := range fields {
+		v.given[key] = true
+	}
+	return nil
+}
+
+// WithDefaults returns the profile with unset or invalid values taken from base
+// A decoded value is unset when its key is missing; values built in code are
+// unset when they are not positive. Speeds and rates must always be positive
+func (v VehicleProfile) WithDefaults(base VehicleProfile) VehicleProfile {
+	if v.Name == "" {
+		v.Name = base.Name
+	}
+	for _, field := range []struct {
+		key             string
+		value, fallback *float64
+		positive        bool
+	}{
+		{"cruiseSpeedMps", &v.CruiseSpeedMps, &base.CruiseSpeedMps, true},
+		{"climbRateMps", &v.ClimbRateMps, &base.ClimbRateMps, true},
+		{"descentRateMps", &v.DescentRateMps, &base.DescentRateMps, true},
+		{"takeoffSeconds", &v.TakeoffSeconds, &base.TakeoffSeconds, false},
+		{"landingSeconds", &v.LandingSeconds, &base.LandingSeconds, false},
+		{"batteryWh", &v.BatteryWh, &base.BatteryWh, false},
+		{"consumptionWhPerKm", &v.ConsumptionWhPerKm, &base.ConsumptionWhPerKm, false},
+		{"reservePercent", &v.ReservePercent, &base.ReservePercent, false},
+		{"chargingSeconds", &v.ChargingSeconds, &base.ChargingSeconds, false},
+	} {
+		unset := *field.value <= 0
+		if v.given != nil {
+			unset = !v.given[field.key] || *field.value < 0 || (field.positive && *field.value == 0)
+		}
+		if unset {
+			*field.value = *field.fallback
+		}
+	}
+	v.given = nil
+	return v
+}
+
+// EstimateDuration estimates how long a flight over a distance takes at cruise
+// speed, including the takeoff and landing overhead
+func (v VehicleProfile) EstimateDuration(distanceMeters float64) time.Duration {
+	seconds := v.TakeoffSeconds + distanceMeters/v.CruiseSpeedMps + v.LandingSeconds
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// LegSeconds returns the time needed for a leg, climbing or descending while
+// cruising; the leg takes as long as the slower of the two
+func (v VehicleProfile) LegSeconds(horizontalMeters, climbMeters float64) float64 {
+	vertical := 0.0
+	if climbMeters > 0 {
+		vertical = climbMeters / v.ClimbRateMps
+	} else if climbMeters < 0 {
+		vertical = -climbMeters / v.DescentRateMps
+	}
+	return math.Max(horizontalMeters/v.CruiseSpeedMps, vertical)
+}
+
+// FlightTiming is the estimated timing of a planned flight
+type FlightTiming struct {
+	Vehicle         string    `json:"vehicle"`
+	DepartureTime   time.Time `json:"departureTime"`
+	ETA             time.Time `json:"eta"`
+	TotalSeconds    float64   `json:"totalSeconds"`    // Departure until landed
+	WaypointSeconds []float64 `json:"waypointSeconds"` // Cumulative time at which each waypoint is reached
+	LegSeconds      []float64 `json:"legSeconds"`      // Time between consecutive waypoints
+}
+
+// Timing estimates the flight time of a route given the horizontal length and
+// the altitude change of every leg between consecutive waypoints
+// At the stop waypoints the drone lands, recharges and takes off again
+func (v VehicleProfile) Timing(legMeters, legClimbs []float64, stops []int, departure time.Time) *FlightTiming {
+	timing := &FlightTiming{
+		Vehicle:         v.Name,
+		DepartureTime:   departure,
+		WaypointSeconds: []float64{v.TakeoffSeconds},
+		LegSeconds:      make([]float64, len(legMeters)),
+	}
+
+	elapsed := v.TakeoffSeconds
+	for i := range legMeters {
+		if containsInt(stops, i) {
+			elapsed += v.LandingSeconds + v.ChargingSeconds + v.TakeoffSeconds
+		}
+		timing.LegSeconds[i] = v.LegSeconds(legMeters[i], legClimbs[i])
+		elapsed += timing.LegSeconds[i]
+		timing.WaypointSeconds = append(timing.WaypointSeconds, elapsed)
+	}
+
+	timing.TotalSeconds = elapsed + v.LandingSeconds
+	timing.ETA = departure.Add(time.Duration(timing.TotalSeconds * float64(time.Second)))
+	return timing
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestVehicleProfileWithDefaults(t *testing.T) {
+	tests := []struct {
+		name        string
+		json        string
+		wantTakeoff float64
+		wantReserve float64
+		wantSpeed   float64
+	}{
+		{"missing values", `{"name": "wing"}`, 30, 20, 15},
+		{"explicit zeros", `{"takeoffSeconds": 0, "reservePercent": 0}`, 0, 0, 15},
+		{"zero speed", `{"cruiseSpeedMps": 0}`, 30, 20, 15},
+		{"negative values", `{"takeoffSeconds": -5, "reservePercent": -1}`, 30, 20, 15},
+		{"given values", `{"takeoffSeconds": 10, "reservePercent": 5, "cruiseSpeedMps": 22}`, 10, 5, 22},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var profile VehicleProfile
+			if err := json.Unmarshal([]byte(tt.json), &profile); err != nil {
+				t.Fatal(err)
+			}
+			profile = profile.WithDefaults(DefaultVehicleProfile)
+			if profile.TakeoffSeconds != tt.wantTakeoff || profile.ReservePercent != tt.wantReserve || profile.CruiseSpeedMps != tt.wantSpeed {
+				t.Errorf("takeoff %v, reserve %v, speed %v; want %v, %v, %v",
+					profile.TakeoffSeconds, profile.ReservePercent, profile.CruiseSpeedMps, tt.wantTakeoff, tt.wantReserve, tt.wantSpeed)
+			}
+		})
+	}
+
+	// Profiles built in code treat values that are not positive as unset
+	profile := VehicleProfile{CruiseSpeedMps: 22}.WithDefaults(DefaultVehicleProfile)
+	if profile.CruiseSpeedMps != 22 || profile.TakeoffSeconds != 30 {
+		t.Errorf("code-built profile: speed %v, takeoff %v; want 22, 30", profile.CruiseSpeedMps, profile.TakeoffSeconds)
+	}
+}
+
+func TestLegSeconds(t *testing.T) {
+	profile := VehicleProfile{CruiseSpeedMps: 10, ClimbRateMps: 2, DescentRateMps: 4}
+	tests := []struct {
+		name       string
+		horizontal float64
+		climb      float64
+		want       float64
+	}{
+		{"level", 300, 0, 30},
+		{"climb slower than cruise", 100, 60, 30},
+		{"climb faster than cruise", 300, 20, 30},
+		{"descent", 0, -120, 30},
+	}
+	for _, tt := range tests {
+		if got := profile.LegSeconds(tt.horizontal, tt.climb); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: LegSeconds = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTiming(t *testing.T) {
+	profile := VehicleProfile{Name: "test", CruiseSpeedMps: 10, ClimbRateMps: 2, DescentRateMps: 4,
+		TakeoffSeconds: 20, LandingSeconds: 10, ChargingSeconds: 600}
+	departure := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// The drone recharges at the second waypoint before flying the second leg
+	timing := profile.Timing([]float64{300, 600}, []float64{60, 0}, []int{1}, departure)
+
+	wantWaypoints := []float64{20, 50, 50 + 10 + 600 + 20 + 60}
+	for i, want := range wantWaypoints {
+		if timing.WaypointSeconds[i] != want {
+			t.Errorf("waypoint %d reached after %v s, want %v", i, timing.WaypointSeconds[i], want)
+		}
+	}
+	if want := wantWaypoints[2] + 10; timing.TotalSeconds != want {
+		t.Errorf("total %v s, want %v", timing.TotalSeconds, want)
+	}
+	if want := departure.Add(750 * time.Second); !timing.ETA.Equal(want) {
+		t.Errorf("ETA %v, want %v", timing.ETA, want)
+	}
+	if got, want := profile.EstimateDuration(900), 120*time.Second; got != want {
+		t.Errorf("EstimateDuration = %v, want %v", got, want)
+	}
+}
+
+func TestLoadVehicleProfile(t *testing.T) {
+	profile, err := LoadVehicleProfile("vehicle_profile.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.Name != "multirotor" || profile.ChargingSeconds != DefaultVehicleProfile.ChargingSeconds {
+		t.Errorf("loaded %q with charging %v s, want multirotor with the default %v s",
+			profile.Name, profile.ChargingSeconds, DefaultVehicleProfile.ChargingSeconds)
+	}
+	if _, err := LoadVehicleProfile("missing.json"); err == nil {
+		t.Error("LoadVehicleProfile of a missing file returned no error")
+	}
+}
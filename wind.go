@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// WindField is a gridded wind forecast in WGS84 longitude/latitude
+// U and V hold the eastward and northward wind components in meters per second
+// for every cell, row by row from south to north
+type WindField struct {
+	MinX     float64   `json:"minX"`     // Longitude of the western edge of the grid
+	MinY     float64   `json:"minY"`     // Latitude of the southern edge of the grid
+	CellSize float64   `json:"cellSize"` // Cell size in degrees
+	NCols    int       `json:"nCols"`
+	NRows    int       `json:"nRows"`
+	U        []float64 `json:"u"`
+	V        []float64 `json:"v"`
+
+	maxSpeed float64 // Highest wind speed in the grid
+}
+
+// LoadWindField reads a wind field from a JSON file
+func LoadWindField(filename string) (*WindField, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var field WindField
+	if err := json.Unmarshal(data, &field); err != nil {
+		return nil, fmt.Errorf("failed to parse wind field: %w", err)
+	}
+
+	cells := field.NCols * field.NRows
+	if field.NCols <= 0 || field.NRows <= 0 || field.CellSize <= 0 {
+		return nil, fmt.Errorf("missing nCols, nRows or cellSize")
+	}
+	if len(field.U) != cells || len(field.V) != cells {
+		return nil, fmt.Errorf("expected %d u and v values, found %d and %d", cells, len(field.U), len(field.V))
+	}
+
+	for i := range field.U {
+		field.maxSpeed = math.Max(field.maxSpeed, math.Hypot(field.U[i], field.V[i]))
+	}
+	return &field, nil
+}
+
+// MaxSpeed returns the highest wind speed in the field in meters per second
+func (w *WindField) MaxSpeed() float64 {
+	if w == nil {
+		return 0
+	}
+	return w.maxSpeed
+}
+
+// At returns the wind components at a point, bilinearly interpolated between
+// cell centers and clamped to the edge of the grid
+func (w *WindField) At(p Point) (float64, float64) {
+	fx := clamp((p.X-w.MinX)/w.CellSize-0.5, 0, float64(w.NCols-1))
+	fy := clamp((p.Y-w.MinY)/w.CellSize-0.5, 0, float64(w.NRows-1))
+	col, row := int(fx), int(fy)
+	col1, row1 := min(col+1, w.NCols-1), min(row+1, w.NRows-1)
+	tx, ty := fx-float64(col), fy-float64(row)
+
+	interpolate := func(values []float64) float64 {
+		bottom := values[row*w.NCols+col]*(1-tx) + values[row*w.NCols+col1]*tx
+		top := values[row1*w.NCols+col]*(1-tx) + values[row1*w.NCols+col1]*tx
+		return bottom*(1-ty) + top*ty
+	}
+	return interpolate(w.U), interpolate(w.V)
+}
+
+// clamp limits a value to the range [low, high]
+func clamp(value, low, high float64) float64 {
+	return math.Max(low, math.Min(high, value))
+}
+
+// groundSpeed returns the ground speed along a track when the drone holds
+// the track at the given airspeed against the wind, or 0 if the crosswind
+// or headwind is too strong to make progress
+// The track direction (dx, dy) is a unit vector in local east/north meters
+func groundSpeed(airspeed, dx, dy, u, v float64) float64 {
+	along := u*dx + v*dy
+	cross := u*dy - v*dx
+	if math.Abs(cross) >= airspeed {
+		return 0
+	}
+	return math.Max(0, along+math.Sqrt(airspeed*airspeed-cross*cross))
+}
+
+// AirMeters returns the horizontal distance between two points expressed in
+// still-air meters: the airtime with the wind times the airspeed
+// Headwind legs cost more than their length and tailwind legs less; legs the
+// drone cannot fly against the wind cost +Inf
+// The wind is sampled every half cell along the leg
+func (w *WindField) AirMeters(from, to Point, airspeed float64) float64 {
+	length := from.HorizontalDistanceMeters(to)
+	if w == nil || length == 0 {
+		return length
+	}
+
+	ex, ey := toLocalMeters(to, (from.Y+to.Y)/2)
+	sx, sy := toLocalMeters(from, (from.Y+to.Y)/2)
+	dx, dy := (ex-sx)/math.Hypot(ex-sx, ey-sy), (ey-sy)/math.Hypot(ex-sx, ey-sy)
+
+	steps := int(math.Ceil(math.Hypot(to.X-from.X, to.Y-from.Y)/(w.CellSize/2))) + 1
+	seconds := 0.0
+	for i := 0; i < steps; i++ {
+		s := (float64(i) + 0.5) / float64(steps)
+		u, v := w.At(Point{X: from.X + s*(to.X-from.X), Y: from.Y + s*(to.Y-from.Y)})
+		speed := groundSpeed(airspeed, dx, dy, u, v)
+		if speed == 0 {
+			return math.Inf(1)
+		}
+		seconds += length / float64(steps) / speed
+	}
+	return seconds * airspeed
+}
+
+// PathAirMeters returns the still-air length of a path, see AirMeters
+func (w *WindField) PathAirMeters(path []Point, airspeed float64) float64 {
+	total := 0.0
+	for i := 0; i < len(path)-1; i++ {
+		total += w.AirMeters(path[i], path[i+1], airspeed)
+	}
+	return total
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// uniformWind returns a 2x2 wind field over (5, 52)-(5.4, 52.4) with the same wind in every cell
+func uniformWind(u, v float64) *WindField {
+	return &WindField{
+		MinX: 5, MinY: 52, CellSize: 0.2, NCols: 2, NRows: 2,
+		U: []float64{u, u, u, u}, V: []float64{v, v, v, v},
+		maxSpeed: math.Hypot(u, v),
+	}
+}
+
+func TestAirMeters(t *testing.T) {
+	const airspeed = 15.0
+	from := Point{X: 5.2, Y: 52.2}
+	tests := []struct {
+		name string
+		wind *WindField
+		to   Point
+		want float64 // Still-air meters per meter of ground distance
+	}{
+		{"still air", nil, Point{X: 5.3, Y: 52.2}, 1},
+		{"tailwind", uniformWind(5, 0), Point{X: 5.3, Y: 52.2}, 15.0 / 20},
+		{"headwind", uniformWind(5, 0), Point{X: 5.1, Y: 52.2}, 15.0 / 10},
+		{"crosswind", uniformWind(5, 0), Point{X: 5.2, Y: 52.3}, 15 / math.Sqrt(15*15-5*5)},
+		{"headwind stronger than the drone", uniformWind(-20, 0), Point{X: 5.3, Y: 52.2}, math.Inf(1)},
+	}
+	for _, tt := range tests {
+		got := tt.wind.AirMeters(from, tt.to, airspeed) / from.HorizontalDistanceMeters(tt.to)
+		if math.Abs(got-tt.want) > 1e-3 && !(math.IsInf(got, 1) && math.IsInf(tt.want, 1)) {
+			t.Errorf("%s: %v air meters per ground meter, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCostModelWindHeuristicIsAdmissible(t *testing.T) {
+	wind := uniformWind(0, 0)
+	rng := rand.New(rand.NewSource(1))
+	for i := range wind.U {
+		wind.U[i], wind.V[i] = rng.Float64()*16-8, rng.Float64()*16-8
+		wind.maxSpeed = math.Max(wind.maxSpeed, math.Hypot(wind.U[i], wind.V[i]))
+	}
+	costs := &CostModel{Wind: wind, Airspeed: 15}
+
+	for n := 0; n < 1000; n++ {
+		from := Point{X: 5 + rng.Float64()*0.4, Y: 52 + rng.Float64()*0.4, Z: rng.Float64() * 120}
+		to := Point{X: 5 + rng.Float64()*0.4, Y: 52 + rng.Float64()*0.4, Z: rng.Float64() * 120}
+		if h, cost := costs.Heuristic(from, to), costs.EdgeCost(from, to); h > cost+1e-6 {
+			t.Fatalf("Heuristic %v exceeds the edge cost %v from %v to %v", h, cost, from, to)
+		}
+	}
+}
+
+func TestLoadWindField(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		json    string
+		wantErr bool
+	}{
+		{"valid", `{"minX": 5, "minY": 52, "cellSize": 0.2, "nCols": 2, "nRows": 1, "u": [3, 0], "v": [4, 1]}`, false},
+		{"missing size", `{"minX": 5, "minY": 52, "nCols": 2, "nRows": 1, "u": [3, 0], "v": [4, 1]}`, true},
+		{"too few values", `{"minX": 5, "minY": 52, "cellSize": 0.2, "nCols": 2, "nRows": 2, "u": [3, 0], "v": [4, 1]}`, true},
+	}
+	for _, tt := range tests {
+		filename := filepath.Join(dir, "wind.json")
+		if err := os.WriteFile(filename, []byte(tt.json), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		field, err := LoadWindField(filename)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if err == nil && field.MaxSpeed() != 5 {
+			t.Errorf("%s: max speed %v, want 5", tt.name, field.MaxSpeed())
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// BufferConfig defines the safety clearance kept around no-fly zones
+type BufferConfig struct {
+	DefaultMeters float64 `json:"defaultMeters"`
+	// Categories maps a GeoJSON localtype or source_txt value to a buffer in meters
+	// A source_txt match takes precedence over a localtype match
+	Categories map[string]float64 `json:"categories,omitempty"`
+}
+
+// LoadBufferConfig reads a buffer configuration from a JSON file
+func LoadBufferConfig(filename string) (BufferConfig, error) {
+	var config BufferConfig
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return config, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("failed to parse buffer config: %w", err)
+	}
+
+	return config, nil
+}
+
+// BufferFor returns the buffer in meters for a zone with the given GeoJSON properties
+func (c BufferConfig) BufferFor(properties map[string]interface{}) float64 {
+	for _, key := range []string{"source_txt", "localtype"} {
+		if value, ok := properties[key].(string); ok {
+			if buffer, ok := c.Categories[value]; ok {
+				return buffer
+			}
+		}
+	}
+	return c.DefaultMeters
+}
@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestBufferFor(t *testing.T) {
+	buffers, err := LoadBufferConfig("nfz_buffers.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffers.Categories["Verboden"] = 75
+
+	tests := []struct {
+		name       string
+		properties map[string]interface{}
+		want       float64
+	}{
+		{"no properties", nil, 50},
+		{"unknown source", map[string]interface{}{"source_txt": "Natura 2000"}, 50},
+		{"source match", map[string]interface{}{"source_txt": "Ongecontroleerde luchthavens en helihavens (Verboden voor open categorie)"}, 100},
+		{"localtype match", map[string]interface{}{"localtype": "Verboden"}, 75},
+		{"source before localtype", map[string]interface{}{
+			"source_txt": "Gecontroleerde luchthavens en/of radioverplichting (Verboden voor open categorie)",
+			"localtype":  "Verboden",
+		}, 200},
+	}
+	for _, tt := range tests {
+		if got := buffers.BufferFor(tt.properties); got != tt.want {
+			t.Errorf("%s: BufferFor = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := LoadBufferConfig("missing.json"); err == nil {
+		t.Error("LoadBufferConfig of a missing file returned no error")
+	}
+}
+
+func TestIsPointInBufferedPolygon(t *testing.T) {
+	// 0.001 degrees of longitude is about 68 m at 52 degrees north
+	zone := squareZone(5.0, 52.0, 0.01)
+	zone.Buffer = 100
+
+	tests := []struct {
+		name  string
+		point Point
+		want  bool
+	}{
+		{"inside", Point{X: 5.005, Y: 52.005}, true},
+		{"within buffer", Point{X: 5.011, Y: 52.005}, true},
+		{"outside buffer", Point{X: 5.012, Y: 52.005}, false},
+	}
+	for _, tt := range tests {
+		if got := IsPointInBufferedPolygon(tt.point, zone); got != tt.want {
+			t.Errorf("%s: IsPointInBufferedPolygon = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	zone.Buffer = 0
+	if IsPointInBufferedPolygon(Point{X: 5.011, Y: 52.005}, zone) {
+		t.Error("point outside an unbuffered zone is blocked")
+	}
+}
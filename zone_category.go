@@ -0,0 +1,36 @@
+package main
+
+// ZoneCategory classifies how a no-fly zone restricts flight
+type ZoneCategory string
+
+const (
+	// ZoneHard zones may never be entered; edges crossing them are blocked
+	ZoneHard ZoneCategory = "hard"
+	// ZoneRestricted zones may be entered at an extra traversal cost
+	ZoneRestricted ZoneCategory = "restricted"
+)
+
+// GeoJSON localtype values used in the nfz-polygons files
+const (
+	localTypeForbidden  = "Verboden"
+	localTypeRestricted = "Beperkt toegestaan"
+)
+
+// categoryForLocalType maps a GeoJSON localtype to a zone category
+// Unknown types are treated as hard zones to stay on the safe side
+func categoryForLocalType(localType string) ZoneCategory {
+	if localType == localTypeRestricted {
+		return ZoneRestricted
+	}
+	return ZoneHard
+}
+
+// isHardZone reports whether a zone blocks flight
+func isHardZone(zone *Polygon) bool {
+	return zone.Category != ZoneRestricted
+}
+
+// isRestrictedZone reports whether a zone adds a traversal cost penalty
+func isRestrictedZone(zone *Polygon) bool {
+	return zone.Category == ZoneRestricted
+}
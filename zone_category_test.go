@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestCategoryForLocalType(t *testing.T) {
+	tests := []struct {
+		localType string
+		want      ZoneCategory
+	}{
+		{localTypeForbidden, ZoneHard},
+		{localTypeRestricted, ZoneRestricted},
+		{"", ZoneHard},
+		{"Onbekend", ZoneHard},
+	}
+	for _, tt := range tests {
+		if got := categoryForLocalType(tt.localType); got != tt.want {
+			t.Errorf("categoryForLocalType(%q) = %s, want %s", tt.localType, got, tt.want)
+		}
+	}
+}
+
+func TestPlanRouteRestrictedPenalty(t *testing.T) {
+	restricted := squareZone(5.14, 52.06, 0.02)
+	restricted.Category = ZoneRestricted
+	opts := testPlanOptions([]Polygon{restricted}, nil)
+	graph := gridGraph(opts.Zones.WithFilter(isHardZone))
+	stops := []Point{{X: 5.02, Y: 52.07}, {X: 5.28, Y: 52.07}}
+
+	opts.RestrictedPenalty = 0
+	through := PlanRoute(graph, stops, opts)
+	if !through.Success || through.RestrictedDistanceMeters == 0 {
+		t.Fatalf("route without a penalty does not cross the restricted zone: %+v", through)
+	}
+
+	opts.RestrictedPenalty = 10
+	around := PlanRoute(graph, stops, opts)
+	if !around.Success {
+		t.Fatalf("no route with a penalty: %s", around.Message)
+	}
+	if around.RestrictedDistanceMeters != 0 {
+		t.Errorf("route with a penalty flies %.0f m inside the restricted zone", around.RestrictedDistanceMeters)
+	}
+	if around.DistanceMeters <= through.DistanceMeters {
+		t.Errorf("detour of %.0f m is not longer than the straight route of %.0f m", around.DistanceMeters, through.DistanceMeters)
+	}
+}
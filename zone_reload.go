@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// noFlyZoneDir is the directory the permanent no-fly zones are loaded from
+const noFlyZoneDir = "nfz-polygons"
+
+// zoneReloadMutex serializes reloads of the no-fly zones
+var zoneReloadMutex sync.Mutex
+
+// ZoneReload is the result of reloading the no-fly zones
+type ZoneReload struct {
+	Changed bool       `json:"changed"`
+	Zones   int        `json:"zones"`            // Zones loaded after the reload
+	Repair  *PRMRepair `json:"repair,omitempty"` // Nil when the zones did not change or no graph is built
+}
+
+// ReloadNoFlyZones loads the no-fly zones from disk again, repairs the PRM
+// graph for the zones that were added or removed and swaps the zones and the
+// graph in together, so every request plans with a graph matching its zones
+func ReloadNoFlyZones() (ZoneReload, error) {
+	zoneReloadMutex.Lock()
+	defer zoneReloadMutex.Unlock()
+
+	zones, err := loadNoFlyZonesFromFiles(globalBufferConfig)
+	if err != nil {
+		return ZoneReload{}, err
+	}
+	newIndex := NewZoneIndex(zones)
+	reload := ZoneReload{Zones: len(zones)}
+
+	prmMutex.RLock()
+	graph, oldIndex := globalPRMGraph, globalZoneIndex
+	prmMutex.RUnlock()
+
+	if newIndex.Signature() == oldIndex.Signature() {
+		log.Println("ℹ️  No-fly zones unchanged, keeping the PRM graph")
+		return reload, nil
+	}
+	reload.Changed = true
+
+	var repaired *PRMGraph
+	if graph != nil {
+		var repair PRMRepair
+		repaired, repair = RepairPRMGraph(graph, oldIndex.WithFilter(isHardZone), newIndex.WithFilter(isHardZone).WithTerrain(globalTerrain))
+		reload.Repair = &repair
+	}
+
+	prmMutex.Lock()
+	globalNoFlyZones = zones
+	globalZoneIndex = newIndex
+	globalPRMGraph = repaired
+	prmMutex.Unlock()
+	log.Printf("✅ Swapped in %d no-fly zones\n", len(zones))
+
+	if repaired != nil {
+		if err := SavePRMGraph(repaired, "prm_graph.json"); err != nil {
+			log.Printf("⚠️  Failed to save graph: %v\n", err)
+		}
+	}
+	return reload, nil
+}
+
+// zoneDirState describes the GeoJSON files in a directory by name, size and
+// modification time, to detect changes without reading them
+func zoneDirState(dir string) string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.geojson"))
+	sort.Strings(files)
+
+	var parts []string
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			parts = append(parts, fmt.Sprintf("%s:%d:%d", filepath.Base(file), info.Size(), info.ModTime().UnixNano()))
+		}
+	}
+	return strings.Join(parts, "|")
+}
+
+// watchNoFlyZones polls the no-fly zone directory and reloads the zones when
+// its files change
+// A change is only picked up once the files are the same on two consecutive
+// polls, so files that are still being written are not loaded half-way
+func watchNoFlyZones(dir string, interval time.Duration) {
+	log.Printf("👀 Watching %s for no-fly zone changes every %s\n", dir, interval)
+	loaded := zoneDirState(dir)
+	pending := loaded
+
+	for range time.Tick(interval) {
+		state := zoneDirState(dir)
+		if state == loaded {
+			pending = loaded
+			continue
+		}
+		if state != pending {
+			log.Printf("ℹ️  Change detected in %s, reloading when the files are stable\n", dir)
+			pending = state
+			continue
+		}
+
+		log.Println("========================================")
+		log.Printf("🔄 Reloading no-fly zones from %s\n", dir)
+		if _, err := ReloadNoFlyZones(); err != nil {
+			log.Printf("❌ Failed to reload no-fly zones: %v\n", err)
+		}
+		log.Println("========================================")
+		loaded = state
+	}
+}
+
+// POST /admin/reloadZones - Reload the no-fly zones and repair the PRM graph
+func reloadZonesHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("========================================")
+	log.Println("🔄 No-fly zone reload request received")
+
+	if r.Method != http.MethodPost {
+		log.Printf("❌ Method not allowed: %s\n", r.Method)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reload, err := ReloadNoFlyZones()
+	if err != nil {
+		log.Printf("❌ Failed to reload no-fly zones: %v\n", err)
+		http.Error(w, "Failed to reload no-fly zones", http.StatusInternalServerError)
+		log.Println("========================================")
+		return
+	}
+	log.Println("========================================")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"reload":  reload,
+	})
+}